@@ -10,10 +10,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	INFINITY_LIFE_TIME = 0xFFFFFFFF
-)
-
 func init() {
 	rootCmd.AddCommand(addrCmd())
 }
@@ -97,6 +93,14 @@ func printAddrEntry(addr *ip.AddrEntry) {
 		s.WriteString(fmt.Sprintf("any %s ", addr.AnycastAddr.String()))
 	}
 
+	if addr.RoutePriority != -1 {
+		s.WriteString(fmt.Sprintf("metric %d ", addr.RoutePriority))
+	}
+
+	if addr.Protocol != ip.AddrProtoUnspec {
+		s.WriteString(fmt.Sprintf("proto %s ", addr.Protocol))
+	}
+
 	if addr.Scope != -1 {
 		s.WriteString(fmt.Sprintf("scope %s ", addr.Scope.String()))
 	}
@@ -117,13 +121,13 @@ func printAddrEntry(addr *ip.AddrEntry) {
 		s.WriteString("\n")
 		s.WriteString("       valid_lft ")
 		i := addr.AddrInfo
-		if i.Valid == INFINITY_LIFE_TIME {
+		if i.Valid == ip.INFINITY_LIFE_TIME {
 			s.WriteString("forever")
 		} else {
 			s.WriteString(fmt.Sprintf("%dsec", i.Valid))
 		}
 		s.WriteString(" preferred_lft ")
-		if i.Prefered == INFINITY_LIFE_TIME {
+		if i.Prefered == ip.INFINITY_LIFE_TIME {
 			s.WriteString("forever")
 		} else {
 			s.WriteString(fmt.Sprintf("%dsec", i.Prefered))
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(addrLabelCmd())
+}
+
+func addrLabelCmd() *cobra.Command {
+	addrLabelCmd := &cobra.Command{
+		Use:     "addrlabel",
+		Aliases: []string{"addrl", "addrla", "addrlab", "addrlabe"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.listAddrLabels)
+		},
+	}
+	addrLabelCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.listAddrLabels)
+		},
+	})
+	addrLabelCmd.AddCommand(&cobra.Command{
+		Use:     "add",
+		Aliases: []string{"a", "ad"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyAddrLabel(args, true) })
+		},
+	})
+	addrLabelCmd.AddCommand(&cobra.Command{
+		Use:     "del",
+		Aliases: []string{"d", "de", "delete"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyAddrLabel(args, false) })
+		},
+	})
+	addrLabelCmd.AddCommand(&cobra.Command{
+		Use:     "flush",
+		Aliases: []string{"f", "fl", "flu", "flus"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.flushAddrLabels)
+		},
+	})
+	return addrLabelCmd
+}
+
+func (c *client) listAddrLabels() {
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListAddrLabels()
+	if err != nil {
+		fmt.Println("failed to list address labels, err:", err)
+		return
+	}
+
+	for _, e := range entries {
+		printAddrLabelEntry(e)
+	}
+}
+
+func (c *client) modifyAddrLabel(args []string, add bool) {
+	e, err := parseAddrLabelArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if add {
+		err = ipcli.AddAddrLabel(e)
+	} else {
+		err = ipcli.DelAddrLabel(e)
+	}
+	if err != nil {
+		fmt.Println("failed to modify address label, err:", err)
+	}
+}
+
+func (c *client) flushAddrLabels() {
+	ipcli := ip.NewWithConn(c.conn)
+	if err := ipcli.FlushAddrLabels(); err != nil {
+		fmt.Println("failed to flush address labels, err:", err)
+	}
+}
+
+func parseAddrLabelArgs(args []string) (*ip.AddrLabelEntry, error) {
+	var e ip.AddrLabelEntry
+	p := newArgParser(args)
+	for p.more() {
+		switch key := p.next(); key {
+		case "prefix":
+			val, err := p.value(key)
+			if err != nil {
+				return nil, err
+			}
+			e.Prefix, e.PrefixLen, err = parsePrefix(val)
+			if err != nil {
+				return nil, err
+			}
+		case "dev":
+			val, err := p.value(key)
+			if err != nil {
+				return nil, err
+			}
+			if e.Ifindex, err = parseIfindex(val); err != nil {
+				return nil, err
+			}
+		case "label":
+			val, err := p.value(key)
+			if err != nil {
+				return nil, err
+			}
+			label, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label \"%s\"", val)
+			}
+			e.Label = uint32(label)
+		default:
+			return nil, fmt.Errorf("unknown argument \"%s\"", key)
+		}
+	}
+	if e.Prefix == nil {
+		return nil, fmt.Errorf("prefix is required")
+	}
+	return &e, nil
+}
+
+func printAddrLabelEntry(e *ip.AddrLabelEntry) {
+	var s strings.Builder
+	if e.Prefix != nil {
+		prefix := e.Prefix.String()
+		if e.Prefix.To4() != nil {
+			prefix = "::ffff:" + prefix
+		}
+		s.WriteString(fmt.Sprintf("prefix %s/%d ", prefix, e.PrefixLen))
+	}
+	if e.Ifindex != 0 {
+		s.WriteString(fmt.Sprintf("dev %s ", ifname(e.Ifindex)))
+	}
+	s.WriteString(fmt.Sprintf("label %d ", e.Label))
+	fmt.Println(s.String())
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"golang.org/x/sys/unix"
+)
+
+// argParser walks through the iproute2 style arguments,
+// like `prefix ::1/128 dev lo label 0`.
+type argParser struct {
+	args []string
+	idx  int
+}
+
+func newArgParser(args []string) *argParser {
+	return &argParser{args: args}
+}
+
+// more reports whether there are arguments left.
+func (p *argParser) more() bool {
+	return p.idx < len(p.args)
+}
+
+// next returns the next argument.
+func (p *argParser) next() string {
+	arg := p.args[p.idx]
+	p.idx++
+	return arg
+}
+
+// peek returns the next argument without taking it.
+func (p *argParser) peek() string {
+	return p.args[p.idx]
+}
+
+// value returns the value of the keyword which has been taken by next.
+func (p *argParser) value(key string) (string, error) {
+	if !p.more() {
+		return "", fmt.Errorf("command line is not complete, missing value of \"%s\"", key)
+	}
+	return p.next(), nil
+}
+
+// parsePrefix parses an address prefix like `10.0.0.0/8`, `::1` or
+// `default`. The prefix length is the full address length when it is
+// omitted.
+func parsePrefix(s string) (net.IP, int, error) {
+	switch s {
+	case "default", "all", "any":
+		return nil, 0, nil
+	}
+
+	addr, bits := s, -1
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("invalid prefix \"%s\"", s)
+		}
+		addr, bits = s[:i], n
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid address \"%s\"", addr)
+	}
+	if !strings.Contains(addr, ":") {
+		ip = ip.To4()
+	}
+	if bits == -1 {
+		bits = len(ip) * 8
+	}
+	if bits > len(ip)*8 {
+		return nil, 0, fmt.Errorf("invalid prefix \"%s\"", s)
+	}
+	return ip, bits, nil
+}
+
+// parseIfindex gets the ifindex of the device by its name.
+func parseIfindex(name string) (int, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("cannot find device \"%s\"", name)
+	}
+	return ifi.Index, nil
+}
+
+// linkNames caches the names of the devices for the monitor, whose events
+// of a device may come after the device is deleted.
+var linkNames = map[int]string{}
+
+// lookupIfname gets the name of the device by its ifindex, from the kernel
+// or the cache of the deleted devices.
+func lookupIfname(ifindex int) (string, bool) {
+	ifi, err := net.InterfaceByIndex(ifindex)
+	if err == nil {
+		return ifi.Name, true
+	}
+	name, ok := linkNames[ifindex]
+	return name, ok
+}
+
+// ifname gets the name of the device by its ifindex,
+// or `if<ifindex>` when the device does not exist.
+func ifname(ifindex int) string {
+	name, ok := lookupIfname(ifindex)
+	if !ok {
+		return fmt.Sprintf("if%d", ifindex)
+	}
+	return name
+}
+
+// prefix returns the value of the keyword as an address prefix.
+func (p *argParser) prefix(key string) (net.IP, int, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parsePrefix(val)
+}
+
+// addr returns the value of the keyword as an address.
+func (p *argParser) addr(key string) (net.IP, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return nil, err
+	}
+	addr, bits, err := parsePrefix(val)
+	if err != nil || addr == nil || bits != len(addr)*8 {
+		return nil, fmt.Errorf("invalid address \"%s\"", val)
+	}
+	return addr, nil
+}
+
+// ifindex returns the ifindex of the device named by the value of the
+// keyword.
+func (p *argParser) ifindex(key string) (int, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return 0, err
+	}
+	return parseIfindex(val)
+}
+
+// uint returns the value of the keyword as an unsigned integer.
+func (p *argParser) uint(key string, bitSize int) (uint64, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(val, 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s \"%s\"", key, val)
+	}
+	return n, nil
+}
+
+// familyName gets the name of the address family.
+func familyName(family int) string {
+	switch family {
+	case syscall.AF_INET:
+		return "inet"
+	case syscall.AF_INET6:
+		return "inet6"
+	case syscall.AF_PACKET:
+		return "link"
+	case unix.AF_MPLS:
+		return "mpls"
+	case syscall.AF_BRIDGE:
+		return "bridge"
+	}
+	return strconv.Itoa(family)
+}
+
+// parseFamily parses the address family from its name.
+func parseFamily(s string) (int, error) {
+	switch s {
+	case "inet":
+		return syscall.AF_INET, nil
+	case "inet6":
+		return syscall.AF_INET6, nil
+	case "link":
+		return syscall.AF_PACKET, nil
+	case "mpls":
+		return unix.AF_MPLS, nil
+	case "bridge":
+		return syscall.AF_BRIDGE, nil
+	}
+	return 0, fmt.Errorf("invalid protocol family \"%s\"", s)
+}
+
+// parseRealms parses the realms like `[FROM/]TO`, whose realms are names
+// or numbers.
+func parseRealms(s string) (uint32, error) {
+	from, to := "0", s
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		from, to = s[:i], s[i+1:]
+	}
+	f, ok := ip.RealmNames.ID(from)
+	if !ok || f > 0xffff {
+		return 0, fmt.Errorf("invalid realms \"%s\"", s)
+	}
+	t, ok := ip.RealmNames.ID(to)
+	if !ok || t > 0xffff {
+		return 0, fmt.Errorf("invalid realms \"%s\"", s)
+	}
+	return ip.MakeRealms(f, t), nil
+}
+
+// formatRealms formats the realms like `FROM/TO`, or `TO` if it's only
+// the destination realm.
+func formatRealms(realms uint32) string {
+	to := ip.RealmNames.Format(ip.RealmsTo(realms))
+	if from := ip.RealmsFrom(realms); from != 0 {
+		return ip.RealmNames.Format(from) + "/" + to
+	}
+	return to
+}
+
+// formatRouteRealms formats the realms of the routes, which are `realm
+// TO` or `realms FROM/TO` like iproute2.
+func formatRouteRealms(realms uint32) string {
+	if ip.RealmsFrom(realms) != 0 {
+		return fmt.Sprintf("realms %s ", formatRealms(realms))
+	}
+	return fmt.Sprintf("realm %s ", formatRealms(realms))
+}
+
+// dsfield returns the value of the keyword as a DS field, like
+// `0x10` or `AF11`.
+func (p *argParser) dsfield(key string) (int, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return 0, err
+	}
+	tos, err := ip.ParseDSField(val)
+	return int(tos), err
+}
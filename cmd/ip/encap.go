@@ -0,0 +1,518 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"golang.org/x/sys/unix"
+)
+
+// encapTypeNames are the names of the LWTUNNEL_ENCAP_* types.
+var encapTypeNames = []string{"none", "mpls", "ip", "ila", "ip6", "seg6", "bpf", "seg6local", "rpl", "ioam6", "xfrm"}
+
+func encapTypeName(typ int) string {
+	if typ >= 0 && typ < len(encapTypeNames) {
+		return encapTypeNames[typ]
+	}
+	return strconv.Itoa(typ)
+}
+
+// parseEncap parses the encapsulation after the `encap` keyword, like
+// `seg6 mode encap segs fc00::1,fc00::2`. It stops at the first
+// argument which does not belong to the encapsulation.
+func parseEncap(p *argParser) (ip.RouteEncap, error) {
+	typ, err := p.value("encap")
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case "mpls":
+		return parseMPLSEncap(p)
+	case "ip":
+		return parseIPEncap(p, syscall.AF_INET)
+	case "ip6":
+		return parseIPEncap(p, syscall.AF_INET6)
+	case "seg6":
+		return parseSeg6Encap(p)
+	case "seg6local":
+		return parseSeg6LocalEncap(p)
+	case "bpf":
+		return parseBPFEncap(p)
+	case "ioam6":
+		return parseIOAM6Encap(p)
+	}
+	return nil, fmt.Errorf("invalid encap type \"%s\"", typ)
+}
+
+// parseMPLSLabels parses the label stack like `100/200`.
+func parseMPLSLabels(s string) ([]uint32, error) {
+	var labels []uint32
+	for _, l := range strings.Split(s, "/") {
+		label, err := strconv.ParseUint(l, 0, 20)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mpls label \"%s\"", s)
+		}
+		labels = append(labels, uint32(label))
+	}
+	return labels, nil
+}
+
+func parseMPLSEncap(p *argParser) (ip.RouteEncap, error) {
+	var e ip.MPLSEncap
+	val, err := p.value("mpls")
+	if err != nil {
+		return nil, err
+	}
+	if e.Labels, err = parseMPLSLabels(val); err != nil {
+		return nil, err
+	}
+	if p.more() && p.peek() == "ttl" {
+		ttl, err := p.uint(p.next(), 8)
+		if err != nil {
+			return nil, err
+		}
+		e.TTL = int(ttl)
+	}
+	return &e, nil
+}
+
+func parseIPEncap(p *argParser, family int) (ip.RouteEncap, error) {
+	e := ip.IPEncap{Family: family}
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "id":
+			e.ID, err = p.uint(p.next(), 64)
+		case "dst", "src":
+			var addr net.IP
+			if addr, err = p.addr(p.next()); err == nil {
+				if key == "dst" {
+					e.Dst = addr
+				} else {
+					e.Src = addr
+				}
+			}
+		case "ttl", "hoplimit", "tos", "tc":
+			var n uint64
+			n, err = p.uint(p.next(), 8)
+			if key == "ttl" || key == "hoplimit" {
+				e.TTL = int(n)
+			} else {
+				e.Tos = int(n)
+			}
+		case "key":
+			p.next()
+			e.Flags |= ip.TUNNEL_KEY
+		case "csum":
+			p.next()
+			e.Flags |= ip.TUNNEL_CSUM
+		case "seq":
+			p.next()
+			e.Flags |= ip.TUNNEL_SEQ
+		default:
+			return &e, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+// parseSegments parses the segment list like `fc00::1,fc00::2`, and
+// reserves the last segment for the original destination like iproute2
+// if it's not encapsulated.
+func parseSegments(s string, reserve bool) ([]net.IP, error) {
+	var segments []net.IP
+	for _, seg := range strings.Split(s, ",") {
+		addr := net.ParseIP(seg)
+		if addr == nil || addr.To4() != nil {
+			return nil, fmt.Errorf("invalid segment \"%s\"", seg)
+		}
+		segments = append(segments, addr)
+	}
+	if reserve {
+		segments = append(segments, net.IPv6zero)
+	}
+	return segments, nil
+}
+
+// parseSRH parses the `segs SEGMENTS [hmac KEYID]` of a segment routing
+// header.
+func parseSRH(p *argParser) (string, uint32, error) {
+	var segs string
+	var hmac uint32
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "segs":
+			segs, err = p.value(p.next())
+		case "hmac":
+			var keyid uint64
+			keyid, err = p.uint(p.next(), 32)
+			hmac = uint32(keyid)
+		default:
+			return segs, hmac, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	return segs, hmac, nil
+}
+
+func parseSeg6Encap(p *argParser) (ip.RouteEncap, error) {
+	var e ip.Seg6Encap
+	var segs string
+loop:
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "mode":
+			var val string
+			if val, err = p.value(p.next()); err == nil {
+				e.Mode, err = ip.ParseSeg6Mode(val)
+			}
+		case "segs":
+			segs, err = p.value(p.next())
+		case "hmac":
+			var keyid uint64
+			keyid, err = p.uint(p.next(), 32)
+			e.HMAC = uint32(keyid)
+		default:
+			break loop
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if segs == "" {
+		return nil, fmt.Errorf("seg6 segments are required")
+	}
+	var err error
+	e.Segments, err = parseSegments(segs, e.Mode == ip.SEG6_IPTUN_MODE_INLINE)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func parseSeg6LocalEncap(p *argParser) (ip.RouteEncap, error) {
+	var e ip.Seg6LocalEncap
+	var segs string
+loop:
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "action":
+			var val string
+			if val, err = p.value(p.next()); err == nil {
+				e.Action, err = ip.ParseSeg6LocalAction(val)
+			}
+		case "srh":
+			p.next()
+			segs, e.HMAC, err = parseSRH(p)
+		case "table", "vrftable":
+			var val string
+			if val, err = p.value(p.next()); err == nil {
+				var table ip.RouteTable
+				table, err = ip.ParseRouteTable(val)
+				if key == "table" {
+					e.Table = int(table)
+				} else {
+					e.VRFTable = int(table)
+				}
+			}
+		case "nh4", "nh6":
+			var addr net.IP
+			if addr, err = p.addr(p.next()); err == nil {
+				if key == "nh4" {
+					e.NH4 = addr
+				} else {
+					e.NH6 = addr
+				}
+			}
+		case "iif":
+			e.Iif, err = p.ifindex(p.next())
+		case "oif":
+			e.Oif, err = p.ifindex(p.next())
+		case "endpoint":
+			p.next()
+			e.BPF, err = parseBPFProg(p)
+		case "count":
+			p.next()
+			e.Counters = &ip.Seg6LocalCounters{}
+		default:
+			break loop
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Action == 0 {
+		return nil, fmt.Errorf("seg6local action is required")
+	}
+	if segs != "" {
+		var err error
+		e.Segments, err = parseSegments(segs, e.Action != ip.SEG6_LOCAL_ACTION_END_B6_ENCAP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+func parseBPFEncap(p *argParser) (ip.RouteEncap, error) {
+	var e ip.BPFEncap
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "in":
+			p.next()
+			e.In, err = parseBPFProg(p)
+		case "out":
+			p.next()
+			e.Out, err = parseBPFProg(p)
+		case "xmit":
+			p.next()
+			e.Xmit, err = parseBPFProg(p)
+		case "headroom":
+			var n uint64
+			n, err = p.uint(p.next(), 32)
+			e.Headroom = int(n)
+		default:
+			return &e, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+// parseBPFProg parses the pinned BPF program like `pinned PATH`, which
+// is named after the pinned file.
+func parseBPFProg(p *argParser) (*ip.BPFProg, error) {
+	key, err := p.value("bpf")
+	if err != nil {
+		return nil, err
+	}
+	if key != "pinned" {
+		return nil, fmt.Errorf("only pinned bpf programs are supported, not \"%s\"", key)
+	}
+	path, err := p.value(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := bpfObjGet(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned bpf program %s, err: %v", path, err)
+	}
+	return &ip.BPFProg{FD: fd, Name: filepath.Base(path)}, nil
+}
+
+// bpfObjGet opens the pinned BPF object by BPF_OBJ_GET.
+func bpfObjGet(path string) (int, error) {
+	pathname, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	attr := struct {
+		pathname  uint64
+		bpfFd     uint32
+		fileFlags uint32
+	}{pathname: uint64(uintptr(unsafe.Pointer(pathname)))}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_OBJ_GET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	// the pathname is only referenced by an integer in attr.
+	runtime.KeepAlive(pathname)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(fd), nil
+}
+
+func parseIOAM6Encap(p *argParser) (ip.RouteEncap, error) {
+	var e ip.IOAM6Encap
+	trace := false
+loop:
+	for p.more() {
+		var err error
+		switch key := p.peek(); key {
+		case "freq":
+			var val string
+			if val, err = p.value(p.next()); err == nil {
+				_, err = fmt.Sscanf(val, "%d/%d", &e.FreqK, &e.FreqN)
+			}
+		case "mode":
+			var val string
+			if val, err = p.value(p.next()); err == nil {
+				e.Mode, err = ip.ParseIOAM6Mode(val)
+			}
+		case "tundst":
+			e.Dst, err = p.addr(p.next())
+		case "trace":
+			p.next()
+			if val, err := p.value(key); err != nil || val != "prealloc" {
+				return nil, fmt.Errorf("only prealloc ioam6 trace is supported")
+			}
+			trace = true
+		case "type":
+			var typ uint64
+			typ, err = p.uint(p.next(), 24)
+			e.TraceType = uint32(typ)
+		case "ns":
+			var ns uint64
+			ns, err = p.uint(p.next(), 16)
+			e.Namespace = uint16(ns)
+		case "size":
+			var size uint64
+			size, err = p.uint(p.next(), 8)
+			e.Size = int(size)
+		default:
+			break loop
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !trace {
+		return nil, fmt.Errorf("ioam6 trace is required")
+	}
+	return &e, nil
+}
+
+// formatEncap formats the encapsulation like iproute2, like
+// `encap seg6 mode encap segs 2 [ fc00::1 fc00::2 ] `.
+func formatEncap(encap ip.RouteEncap) string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("encap %s ", encapTypeName(encap.EncapType())))
+
+	switch e := encap.(type) {
+	case *ip.MPLSEncap:
+		s.WriteString(formatMPLSLabels(e.Labels) + " ")
+		if e.TTL != 0 {
+			s.WriteString(fmt.Sprintf("ttl %d ", e.TTL))
+		}
+
+	case *ip.IPEncap:
+		s.WriteString(fmt.Sprintf("id %d ", e.ID))
+		if e.Src != nil {
+			s.WriteString(fmt.Sprintf("src %s ", e.Src))
+		}
+		if e.Dst != nil {
+			s.WriteString(fmt.Sprintf("dst %s ", e.Dst))
+		}
+		ttl, tos := "ttl", "tos"
+		if e.Family == syscall.AF_INET6 {
+			ttl, tos = "hoplimit", "tc"
+		}
+		if e.TTL != 0 {
+			s.WriteString(fmt.Sprintf("%s %d ", ttl, e.TTL))
+		}
+		if e.Tos != 0 {
+			s.WriteString(fmt.Sprintf("%s %d ", tos, e.Tos))
+		}
+		if e.Flags&ip.TUNNEL_KEY != 0 {
+			s.WriteString("key ")
+		}
+		if e.Flags&ip.TUNNEL_CSUM != 0 {
+			s.WriteString("csum ")
+		}
+		if e.Flags&ip.TUNNEL_SEQ != 0 {
+			s.WriteString("seq ")
+		}
+
+	case *ip.Seg6Encap:
+		s.WriteString(fmt.Sprintf("mode %s ", e.Mode))
+		s.WriteString(formatSRH(e.Segments, e.HMAC))
+
+	case *ip.Seg6LocalEncap:
+		s.WriteString(fmt.Sprintf("action %s ", e.Action))
+		if len(e.Segments) != 0 {
+			s.WriteString(formatSRH(e.Segments, e.HMAC))
+		}
+		if e.Table != 0 {
+			s.WriteString(fmt.Sprintf("table %s ", ip.RouteTable(e.Table)))
+		}
+		if e.VRFTable != 0 {
+			s.WriteString(fmt.Sprintf("vrftable %s ", ip.RouteTable(e.VRFTable)))
+		}
+		if e.NH4 != nil {
+			s.WriteString(fmt.Sprintf("nh4 %s ", e.NH4))
+		}
+		if e.NH6 != nil {
+			s.WriteString(fmt.Sprintf("nh6 %s ", e.NH6))
+		}
+		if e.Iif != 0 {
+			s.WriteString(fmt.Sprintf("iif %s ", ifname(e.Iif)))
+		}
+		if e.Oif != 0 {
+			s.WriteString(fmt.Sprintf("oif %s ", ifname(e.Oif)))
+		}
+		if e.BPF != nil {
+			s.WriteString(fmt.Sprintf("endpoint %s ", e.BPF.Name))
+		}
+
+	case *ip.BPFEncap:
+		for _, prog := range []struct {
+			name string
+			prog *ip.BPFProg
+		}{
+			{"in", e.In},
+			{"out", e.Out},
+			{"xmit", e.Xmit},
+		} {
+			if prog.prog != nil {
+				s.WriteString(fmt.Sprintf("%s %s ", prog.name, prog.prog.Name))
+			}
+		}
+		if e.Headroom != 0 {
+			s.WriteString(fmt.Sprintf("headroom %d ", e.Headroom))
+		}
+
+	case *ip.IOAM6Encap:
+		s.WriteString(fmt.Sprintf("freq %d/%d mode %s ", e.FreqK, e.FreqN, e.Mode))
+		if e.Mode != ip.IOAM6_IPTUNNEL_MODE_INLINE && e.Dst != nil {
+			s.WriteString(fmt.Sprintf("tundst %s ", e.Dst))
+		}
+		s.WriteString(fmt.Sprintf("trace prealloc type %#08x ns %d size %d ",
+			e.TraceType, e.Namespace, e.Size))
+	}
+	return s.String()
+}
+
+// formatMPLSLabels formats the label stack like `100/200`.
+func formatMPLSLabels(labels []uint32) string {
+	s := make([]string, 0, len(labels))
+	for _, label := range labels {
+		s = append(s, strconv.FormatUint(uint64(label), 10))
+	}
+	return strings.Join(s, "/")
+}
+
+// formatSRH formats the segments like `segs 2 [ fc00::1 fc00::2 ] `.
+func formatSRH(segments []net.IP, hmac uint32) string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("segs %d [ ", len(segments)))
+	for _, seg := range segments {
+		s.WriteString(fmt.Sprintf("%s ", seg))
+	}
+	s.WriteString("] ")
+	if hmac != 0 {
+		s.WriteString(fmt.Sprintf("hmac %X ", hmac))
+	}
+	return s.String()
+}
@@ -29,6 +29,13 @@ func linkCmd() *cobra.Command {
 			cli.runCmd(cli.listLinks)
 		},
 	})
+	linkCmd.AddCommand(&cobra.Command{
+		Use:     "set",
+		Aliases: []string{"se"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.setLink(args) })
+		},
+	})
 	return linkCmd
 }
 
@@ -58,6 +65,53 @@ func (c *client) listLinks() {
 	}
 }
 
+func (c *client) setLink(args []string) {
+	var ifindex int
+	var mode *ip.AddrGenMode
+	p := newArgParser(args)
+	for p.more() {
+		switch key := p.next(); key {
+		case "addrgenmode":
+			val, err := p.value(key)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			m, err := ip.ParseAddrGenMode(val)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			mode = &m
+		default:
+			if key == "dev" {
+				val, err := p.value(key)
+				if err != nil {
+					fmt.Println(err)
+					return
+				}
+				key = val
+			}
+			var err error
+			if ifindex, err = parseIfindex(key); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+	}
+	if ifindex == 0 {
+		fmt.Println("device is required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if mode != nil {
+		if err := ipcli.SetAddrGenMode(ifindex, *mode); err != nil {
+			fmt.Println("failed to set addrgenmode, err:", err)
+		}
+	}
+}
+
 func printLinkEntry(e *ip.LinkEntry) {
 	if e.Name == "" {
 		return
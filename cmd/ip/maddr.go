@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(maddrCmd())
+}
+
+func maddrCmd() *cobra.Command {
+	maddrCmd := &cobra.Command{
+		Use:     "maddress",
+		Aliases: []string{"m", "ma", "mad", "madd", "maddr", "maddre", "maddres"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listMaddrs(args) })
+		},
+	}
+	maddrCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listMaddrs(args) })
+		},
+	})
+	maddrCmd.AddCommand(&cobra.Command{
+		Use:     "add",
+		Aliases: []string{"a", "ad"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyMaddr(args, true) })
+		},
+	})
+	maddrCmd.AddCommand(&cobra.Command{
+		Use:     "del",
+		Aliases: []string{"d", "de", "delete"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyMaddr(args, false) })
+		},
+	})
+	return maddrCmd
+}
+
+func (c *client) listMaddrs(args []string) {
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		key := p.next()
+		if key == "dev" {
+			val, err := p.value(key)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			key = val
+		}
+		var err error
+		if ifindex, err = parseIfindex(key); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListMaddrs()
+	if err != nil {
+		fmt.Println("failed to list multicast addresses, err:", err)
+		return
+	}
+
+	maddrs := make(map[int][]*ip.MaddrEntry)
+	for _, e := range entries {
+		if ifindex == 0 || e.Ifindex == ifindex {
+			maddrs[e.Ifindex] = append(maddrs[e.Ifindex], e)
+		}
+	}
+
+	ifindexs := make([]int, 0, len(maddrs))
+	for ifindex := range maddrs {
+		ifindexs = append(ifindexs, ifindex)
+	}
+	sort.Ints(ifindexs)
+
+	for _, ifindex := range ifindexs {
+		fmt.Printf("%d:\t%s\n", ifindex, ifname(ifindex))
+		for _, e := range maddrs[ifindex] {
+			printMaddrEntry(e)
+		}
+	}
+}
+
+func (c *client) modifyMaddr(args []string, add bool) {
+	var lladdr net.HardwareAddr
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		switch key := p.next(); key {
+		case "dev":
+			val, err := p.value(key)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if ifindex, err = parseIfindex(val); err != nil {
+				fmt.Println(err)
+				return
+			}
+		default:
+			if key == "address" {
+				val, err := p.value(key)
+				if err != nil {
+					fmt.Println(err)
+					return
+				}
+				key = val
+			}
+			addr, err := net.ParseMAC(key)
+			if err != nil {
+				fmt.Printf("invalid link-layer address \"%s\"\n", key)
+				return
+			}
+			lladdr = addr
+		}
+	}
+	if lladdr == nil || ifindex == 0 {
+		fmt.Println("multicast address and device are required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	var err error
+	if add {
+		err = ipcli.AddMaddr(ifindex, lladdr)
+	} else {
+		err = ipcli.DelMaddr(ifindex, lladdr)
+	}
+	if err != nil {
+		fmt.Println("failed to modify multicast address, err:", err)
+	}
+}
+
+func printMaddrEntry(e *ip.MaddrEntry) {
+	var s strings.Builder
+	switch e.Family {
+	case syscall.AF_PACKET:
+		s.WriteString(fmt.Sprintf("\tlink  %s", e.Lladdr))
+	case syscall.AF_INET:
+		s.WriteString(fmt.Sprintf("\tinet  %s", e.Addr))
+	default:
+		s.WriteString(fmt.Sprintf("\tinet6 %s", e.Addr))
+	}
+	if e.Users > 1 {
+		s.WriteString(fmt.Sprintf(" users %d", e.Users))
+	}
+	if e.Static {
+		s.WriteString(" static")
+	}
+	fmt.Println(s.String())
+}
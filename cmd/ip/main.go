@@ -2,14 +2,24 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"syscall"
 
 	"github.com/Asphaltt/go-iproute2"
 	"github.com/mdlayher/netlink"
 	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
 )
 
 var cli client
 
+// preferredFamily is the protocol family given by -f, -4, -6 or -M.
+var preferredFamily = syscall.AF_UNSPEC
+
+// showStats is the times of -s, which shows more statistics.
+var showStats int
+
 type client struct {
 	conn *netlink.Conn
 }
@@ -35,6 +45,51 @@ var rootCmd = cobra.Command{
 	Use: "ip",
 }
 
+func init() {
+	var family string
+	var inet4, inet6, mpls bool
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&family, "family", "f", "", "protocol family: inet, inet6, mpls, link or bridge")
+	flags.BoolVarP(&inet4, "4", "4", false, "shortcut for -family inet")
+	flags.BoolVarP(&inet6, "6", "6", false, "shortcut for -family inet6")
+	flags.BoolVarP(&mpls, "mpls", "M", false, "shortcut for -family mpls")
+	flags.CountVarP(&showStats, "stats", "s", "show more statistics, repeat it for more")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		switch {
+		case family != "":
+			f, err := parseFamily(family)
+			if err != nil {
+				return err
+			}
+			preferredFamily = f
+		case inet4:
+			preferredFamily = syscall.AF_INET
+		case inet6:
+			preferredFamily = syscall.AF_INET6
+		case mpls:
+			preferredFamily = unix.AF_MPLS
+		}
+		return nil
+	}
+}
+
+// normalizeArgs turns the option -ts into --tshort, which is parsed as
+// -t -s by pflag. Only the options before the object are looked at, like
+// iproute2, so the arguments of the commands are kept.
+func normalizeArgs(args []string) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-ts" || arg == "-tshort":
+			args[i] = "--tshort"
+		case arg == "-f" || arg == "--family":
+			i++
+		case arg == "--" || !strings.HasPrefix(arg, "-"):
+			return
+		}
+	}
+}
+
 func main() {
+	normalizeArgs(os.Args[1:])
 	rootCmd.Execute()
 }
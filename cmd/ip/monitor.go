@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+// timestamp is given by -t, which prints the time of every event in a
+// line, and timestampShort by -ts, which prints it before the event.
+var timestamp, timestampShort bool
+
+func init() {
+	rootCmd.AddCommand(monitorCmd())
+
+	flags := rootCmd.PersistentFlags()
+	flags.BoolVarP(&timestamp, "timestamp", "t", false, "print the timestamp of the monitor events")
+	flags.BoolVar(&timestampShort, "tshort", false, "print the short timestamp of the monitor events")
+}
+
+func monitorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "monitor",
+		Aliases: []string{"mo", "mon", "moni", "monit", "monito"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.monitor(args) })
+		},
+	}
+}
+
+func (c *client) monitor(args []string) {
+	var objects ip.MonitorObject
+	var label bool
+	for _, arg := range args {
+		switch arg {
+		case "label":
+			label = true
+		case "addr":
+			objects |= ip.MonitorAddress
+		default:
+			obj, err := ip.ParseMonitorObject(arg)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			objects |= obj
+		}
+	}
+	if objects == 0 {
+		objects = ip.MonitorAll
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	links, err := ipcli.ListLinks()
+	if err != nil {
+		fmt.Println("failed to list links, err:", err)
+		return
+	}
+	for _, e := range links {
+		linkNames[e.Ifindex] = e.Name
+	}
+
+	// the links are always monitored to cache the names of the devices.
+	err = ipcli.Monitor(objects|ip.MonitorLink, func(ev *ip.MonitorEvent) {
+		if ev.Action == ip.MonitorActionOverrun {
+			fmt.Println("netlink receive error:", unix.ENOBUFS)
+			return
+		}
+		if e, ok := ev.Object.(*ip.LinkEntry); ok {
+			if e.Name != "" {
+				linkNames[e.Ifindex] = e.Name
+			}
+			if objects&ip.MonitorLink == 0 {
+				return
+			}
+		}
+		if preferredFamily != syscall.AF_UNSPEC {
+			if family, ok := monitorEventFamily(ev); ok && family != preferredFamily {
+				return
+			}
+		}
+		printMonitorEvent(ev, label)
+	})
+	if err != nil {
+		fmt.Println("failed to monitor, err:", err)
+	}
+}
+
+// monitorEventFamily returns the family of the object of the event, the
+// links, the nsids and the stats have no family.
+func monitorEventFamily(ev *ip.MonitorEvent) (int, bool) {
+	switch e := ev.Object.(type) {
+	case *ip.AddrEntry:
+		return e.Family, true
+	case *ip.RouteEntry:
+		return e.Family, true
+	case *ip.NeighEntry:
+		return e.Family, true
+	case *ip.RuleEntry:
+		return e.Family, true
+	case *ip.NexthopEntry:
+		return e.Family, true
+	case *ip.NetconfEntry:
+		return e.Family, true
+	case *ip.PrefixEntry:
+		return e.Family, true
+	}
+	return 0, false
+}
+
+func printMonitorTimestamp() {
+	now := time.Now()
+	switch {
+	case timestampShort:
+		fmt.Printf("[%s.%06d] ", now.Format("2006-01-02T15:04:05"), now.Nanosecond()/1000)
+	case timestamp:
+		fmt.Printf("Timestamp: %s %d usec\n", now.Format("Mon Jan _2 15:04:05 2006"), now.Nanosecond()/1000)
+	}
+}
+
+func printMonitorEvent(ev *ip.MonitorEvent, label bool) {
+	prefix := func(name string) {
+		printMonitorTimestamp()
+		if label {
+			fmt.Printf("[%s]", name)
+		}
+		if ev.Action == ip.MonitorActionDel {
+			fmt.Print("Deleted ")
+		}
+	}
+
+	switch e := ev.Object.(type) {
+	case *ip.LinkEntry:
+		if e.Name == "" {
+			return
+		}
+		prefix("LINK")
+		printLinkEntry(e)
+	case *ip.AddrEntry:
+		prefix("ADDR")
+		fmt.Printf("%d: %s", e.Ifindex, ifname(e.Ifindex))
+		printAddrEntry(e)
+	case *ip.RouteEntry:
+		prefix("ROUTE")
+		printRouteEntry(e, nil)
+	case *ip.NeighEntry:
+		prefix("NEIGH")
+		printNeighEntry(e, &ip.NeighFilter{})
+	case *ip.RuleEntry:
+		prefix("RULE")
+		printRuleEntry(e)
+	case *ip.NexthopEntry:
+		prefix("NEXTHOP")
+		printNexthopEntry(e)
+	case *ip.NetconfEntry:
+		prefix("NETCONF")
+		printNetconfEntry(e)
+	case *ip.NSIDEntry:
+		prefix("NSID")
+		fmt.Printf("nsid %d\n", e.NSID)
+	case *ip.PrefixEntry:
+		prefix("PREFIX")
+		printPrefixEntry(e)
+	case *ip.LinkStatsEntry:
+		prefix("STATS")
+		fmt.Printf("%d: %s: filter_mask 0x%x\n", e.Ifindex, ifname(e.Ifindex), e.FilterMask)
+	}
+}
+
+func printPrefixEntry(e *ip.PrefixEntry) {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("prefix %s/%d ", e.Prefix, e.PrefixLen))
+	s.WriteString(fmt.Sprintf("dev %s ", ifname(e.Ifindex)))
+	if e.OnLink {
+		s.WriteString("onlink ")
+	}
+	if e.Autoconf {
+		s.WriteString("autoconf ")
+	}
+	s.WriteString(fmt.Sprintf("valid %d ", e.ValidTime))
+	s.WriteString(fmt.Sprintf("preferred %d ", e.PreferredTime))
+	fmt.Println(s.String())
+}
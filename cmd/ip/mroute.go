@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	rootCmd.AddCommand(mrouteCmd())
+}
+
+func mrouteCmd() *cobra.Command {
+	mrouteCmd := &cobra.Command{
+		Use:     "mroute",
+		Aliases: []string{"mr", "mro", "mrou", "mrout"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listMroutes(args) })
+		},
+	}
+	mrouteCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listMroutes(args) })
+		},
+	})
+	return mrouteCmd
+}
+
+func (c *client) listMroutes(args []string) {
+	filter, err := parseMrouteFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListMroutes(filter)
+	if err != nil {
+		fmt.Println("failed to list multicast routes, err:", err)
+		return
+	}
+
+	for _, e := range entries {
+		printMrouteEntry(e, filter)
+	}
+}
+
+// parseMrouteFilter parses the multicast route selectors, like
+// `iif eth0 from 10.0.0.0/8 to 239.0.0.0/8`.
+func parseMrouteFilter(args []string) (*ip.MrouteFilter, error) {
+	var f ip.MrouteFilter
+	f.Family = preferredFamily
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "table":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			if val == "all" {
+				f.AllTables = true
+			} else {
+				f.Table, err = ip.ParseRouteTable(val)
+			}
+		case "iif":
+			f.InIfindex, err = p.ifindex(key)
+		case "from":
+			f.Src, f.SrcLen, err = p.prefix(key)
+		case "to":
+			f.Dst, f.DstLen, err = p.prefix(key)
+		default:
+			f.Dst, f.DstLen, err = parsePrefix(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// formatMrouteAge formats the age of the multicast route in seconds with
+// two decimals, like iproute2.
+func formatMrouteAge(d time.Duration) string {
+	centis := int64(d / (10 * time.Millisecond))
+	return fmt.Sprintf("%d.%02d", centis/100, centis%100)
+}
+
+func printMrouteEntry(e *ip.MrouteEntry, f *ip.MrouteFilter) {
+	var s strings.Builder
+
+	src, dst := "unknown", "unknown"
+	if e.Src != nil {
+		src = e.Src.String()
+	}
+	if e.Dst != nil {
+		dst = e.Dst.String()
+	}
+	s.WriteString(fmt.Sprintf("%-32s Iif: ", "("+src+","+dst+")"))
+
+	if e.InIfindex != 0 {
+		s.WriteString(fmt.Sprintf("%-10s ", ifname(e.InIfindex)))
+	} else {
+		s.WriteString("unresolved ")
+	}
+
+	for i, oif := range e.OutIfs {
+		if i == 0 {
+			s.WriteString("Oifs: ")
+		}
+		s.WriteString(ifname(oif.Ifindex))
+		if oif.TTL > 1 {
+			s.WriteString(fmt.Sprintf("(ttl %d) ", oif.TTL))
+		} else {
+			s.WriteString(" ")
+		}
+	}
+
+	if e.Resolved() {
+		s.WriteString(" State: resolved")
+	} else {
+		s.WriteString(" State: unresolved")
+	}
+	if int(e.Flags)&unix.RTNH_F_OFFLOAD != 0 {
+		s.WriteString(" offload")
+	}
+
+	if showStats > 0 && e.Stats != nil {
+		s.WriteString(fmt.Sprintf("\n  %d packets, %d bytes", e.Stats.Packets, e.Stats.Bytes))
+		if e.Stats.WrongIf != 0 {
+			s.WriteString(fmt.Sprintf(", %d arrived on wrong iif.", e.Stats.WrongIf))
+		}
+	}
+	if showStats > 0 && e.Age != 0 {
+		s.WriteString(fmt.Sprintf(", Age %s", formatMrouteAge(e.Age)))
+	}
+
+	if f.AllTables && e.Table != unix.RT_TABLE_MAIN {
+		s.WriteString(fmt.Sprintf(" Table: %s", e.Table))
+	}
+	fmt.Println(s.String())
+}
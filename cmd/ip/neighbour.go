@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/Asphaltt/go-iproute2"
 	"github.com/Asphaltt/go-iproute2/ip"
@@ -19,46 +20,320 @@ func neighCmd() *cobra.Command {
 		Use:     "neighbour",
 		Aliases: []string{"n", "ne", "nei", "neig", "neigh"},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli.runCmd(cli.listNeighbours)
+			cli.runCmd(func() { cli.listNeighbours(args) })
 		},
 	}
 	neighCmd.AddCommand(&cobra.Command{
 		Use:     "list",
 		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli.runCmd(cli.listNeighbours)
+			cli.runCmd(func() { cli.listNeighbours(args) })
 		},
 	})
+	neighCmd.AddCommand(&cobra.Command{
+		Use:     "get",
+		Aliases: []string{"g", "ge"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.getNeighbour(args) })
+		},
+	})
+	neighCmd.AddCommand(&cobra.Command{
+		Use:     "flush",
+		Aliases: []string{"f", "fl", "flu", "flus"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.flushNeighbours(args) })
+		},
+	})
+
+	modifyCmds := []struct {
+		use     string
+		aliases []string
+		fn      func(*ip.Client, *ip.NeighEntry) error
+	}{
+		{"add", []string{"a", "ad"}, (*ip.Client).AddNeighbour},
+		{"del", []string{"d", "de", "delete"}, (*ip.Client).DelNeighbour},
+		{"replace", []string{"repl"}, (*ip.Client).ReplaceNeighbour},
+		{"change", []string{"chg"}, (*ip.Client).ChangeNeighbour},
+	}
+	for _, m := range modifyCmds {
+		m := m
+		neighCmd.AddCommand(&cobra.Command{
+			Use:     m.use,
+			Aliases: m.aliases,
+			Run: func(cmd *cobra.Command, args []string) {
+				cli.runCmd(func() { cli.modifyNeighbour(args, m.fn) })
+			},
+		})
+	}
 	return neighCmd
 }
 
-func (c *client) listNeighbours() {
+func (c *client) listNeighbours(args []string) {
+	filter, err := parseNeighFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	ipcli := ip.NewWithConn(c.conn)
-	entries, err := ipcli.ListNeighbours()
+	entries, err := ipcli.ListNeighboursWithFilter(filter)
 	if err != nil {
 		fmt.Println("failed to list neighbour entries, err:", err)
 		return
 	}
 
 	for _, e := range entries {
-		if e.State != iproute2.NudNoArp {
-			printNeighEntry(e)
+		printNeighEntry(e, filter)
+	}
+}
+
+func (c *client) flushNeighbours(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Flush requires arguments.")
+		return
+	}
+	filter, err := parseNeighFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	n, err := ipcli.FlushNeighbours(filter)
+	if err != nil {
+		fmt.Println("failed to flush neighbours, err:", err)
+		return
+	}
+	if showStats > 0 {
+		fmt.Printf("*** Flush is complete, %d neighbours deleted ***\n", n)
+	}
+}
+
+func (c *client) getNeighbour(args []string) {
+	var dst net.IP
+	var ifindex int
+	var opts ip.NeighGetOptions
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "dev":
+			ifindex, err = p.ifindex(key)
+		case "proxy":
+			opts.Proxy = true
+			dst, err = p.addr(key)
+		case "to":
+			dst, err = p.addr(key)
+		default:
+			var bits int
+			dst, bits, err = parsePrefix(key)
+			if err == nil && (dst == nil || bits != len(dst)*8) {
+				err = fmt.Errorf("invalid address \"%s\"", key)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if dst == nil || ifindex == 0 && !opts.Proxy {
+		fmt.Println("Device and address are required arguments.")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	e, err := ipcli.GetNeighbour(dst, ifindex, &opts)
+	if err != nil {
+		fmt.Println("failed to get neighbour, err:", err)
+		return
+	}
+	printNeighEntry(e, &ip.NeighFilter{})
+}
+
+func (c *client) modifyNeighbour(args []string, fn func(*ip.Client, *ip.NeighEntry) error) {
+	e, err := parseNeighArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := fn(ipcli, e); err != nil {
+		fmt.Println("failed to modify neighbour, err:", err)
+	}
+}
+
+// parseNeighArgs parses the neighbour to modify, like
+// `10.0.0.1 lladdr 02:00:00:00:00:01 dev eth0 nud reachable router`.
+// The state is permanent if it's not given, like iproute2.
+func parseNeighArgs(args []string) (*ip.NeighEntry, error) {
+	var e ip.NeighEntry
+	e.Family = preferredFamily
+	e.State = iproute2.NudPermanent
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "lladdr":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			if e.Lladdr, err = net.ParseMAC(val); err != nil {
+				err = fmt.Errorf("invalid lladdr \"%s\"", val)
+			}
+		case "nud":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			e.State, err = iproute2.ParseNudState(val)
+		case "proxy":
+			e.Flags |= iproute2.NtfProxy
+			e.Addr, err = p.addr(key)
+		case "router":
+			e.Flags |= iproute2.NtfRouter
+		case "use":
+			e.Flags |= iproute2.NtfUse
+		case "extern_learn":
+			e.Flags |= iproute2.NtfExtLearned
+		case "managed":
+			e.FlagsExt |= iproute2.NtfExtManaged
+		case "dev":
+			e.Ifindex, err = p.ifindex(key)
+		case "protocol":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			e.Protocol, err = ip.ParseRouteProtocol(val)
+		case "to":
+			e.Addr, err = p.addr(key)
+		default:
+			var bits int
+			e.Addr, bits, err = parsePrefix(key)
+			if err == nil && (e.Addr == nil || bits != len(e.Addr)*8) {
+				err = fmt.Errorf("invalid address \"%s\"", key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Ifindex == 0 || e.Addr == nil {
+		return nil, fmt.Errorf("Device and destination are required arguments.")
+	}
+	return &e, nil
+}
+
+// parseNeighFilter parses the neighbour selectors, like
+// `dev eth0 nud reachable nud stale to 10.0.0.0/8`.
+func parseNeighFilter(args []string) (*ip.NeighFilter, error) {
+	var f ip.NeighFilter
+	f.Family = preferredFamily
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "dev":
+			f.Ifindex, err = p.ifindex(key)
+		case "master", "vrf":
+			f.Master, err = p.ifindex(key)
+		case "nud":
+			var state iproute2.NudState
+			if state, err = parseNudState(p, key); err == nil {
+				f.State |= state
+			}
+		case "proxy":
+			f.Proxy = true
+		case "unused":
+			f.Unused = true
+		case "to":
+			f.Dst, f.DstLen, err = p.prefix(key)
+		default:
+			f.Dst, f.DstLen, err = parsePrefix(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// parseNudState parses the value of the keyword as the states selected,
+// which may be `all` or `none`.
+func parseNudState(p *argParser, key string) (iproute2.NudState, error) {
+	val, err := p.value(key)
+	if err != nil {
+		return 0, err
+	}
+	if val == "all" {
+		return ip.NeighStateAll, nil
+	}
+	state, err := iproute2.ParseNudState(val)
+	if err != nil {
+		return 0, err
+	}
+	if state == iproute2.NudNone {
+		return ip.NeighStateNone, nil
+	}
+	return state, nil
+}
+
+// formatNudState formats the states like iproute2, every state is in
+// upper case and followed by a space.
+func formatNudState(state iproute2.NudState) string {
+	var s strings.Builder
+	for st := iproute2.NudIncomplete; st <= iproute2.NudPermanent; st <<= 1 {
+		if state&st != 0 {
+			s.WriteString(strings.ToUpper(st.String()))
+			s.WriteByte(' ')
 		}
 	}
+	return s.String()
 }
 
-func printNeighEntry(e *ip.NeighEntry) {
-	var b strings.Builder
-	b.WriteString(e.Addr.String())
+func printNeighEntry(e *ip.NeighEntry, f *ip.NeighFilter) {
+	var s strings.Builder
+	s.WriteString(e.Addr.String() + " ")
+
+	if f.Ifindex == 0 && e.Ifindex != 0 {
+		s.WriteString(fmt.Sprintf("dev %s ", ifname(e.Ifindex)))
+	}
+	if e.Lladdr != nil {
+		s.WriteString(fmt.Sprintf("lladdr %s ", e.Lladdr))
+	}
+
+	for _, flag := range []iproute2.NtfFlag{
+		iproute2.NtfRouter,
+		iproute2.NtfProxy,
+		iproute2.NtfExtLearned,
+		iproute2.NtfOffloaded,
+	} {
+		if e.Flags&flag != 0 {
+			s.WriteString(flag.String() + " ")
+		}
+	}
+	if e.FlagsExt&iproute2.NtfExtManaged != 0 {
+		s.WriteString(iproute2.NtfExtManaged.String() + " ")
+	}
 
-	if e.Ifindex != 0 {
-		ifc, _ := net.InterfaceByIndex(e.Ifindex)
-		b.WriteString(fmt.Sprintf(" dev %s", ifc.Name))
+	if showStats > 0 && e.CacheInfo != nil {
+		ci := e.CacheInfo
+		if ci.RefCount != 0 {
+			s.WriteString(fmt.Sprintf("ref %d ", ci.RefCount))
+		}
+		s.WriteString(fmt.Sprintf("used %d/%d/%d ",
+			ci.Used/time.Second, ci.Confirmed/time.Second, ci.Updated/time.Second))
+	}
+	if showStats > 0 && e.Probes >= 0 {
+		s.WriteString(fmt.Sprintf("probes %d ", e.Probes))
 	}
 
-	if e.State != iproute2.NudFailed {
-		b.WriteString(fmt.Sprintf(" lladdr %s", e.Lladdr))
+	s.WriteString(formatNudState(e.State))
+	if e.Protocol != 0 {
+		s.WriteString(fmt.Sprintf("proto %s ", e.Protocol))
 	}
-	b.WriteString(fmt.Sprintf(" %s", strings.ToUpper(e.State.String())))
-	fmt.Println(b.String())
+	fmt.Println(s.String())
 }
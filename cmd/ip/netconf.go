@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(netconfCmd())
+}
+
+func netconfCmd() *cobra.Command {
+	netconfCmd := &cobra.Command{
+		Use:     "netconf",
+		Aliases: []string{"netc", "netco", "netcon"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNetconfs(args) })
+		},
+	}
+	netconfCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNetconfs(args) })
+		},
+	})
+	return netconfCmd
+}
+
+func (c *client) listNetconfs(args []string) {
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "dev":
+			ifindex, err = p.ifindex(key)
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if ifindex == 0 {
+		entries, err := ipcli.ListNetconfs(preferredFamily)
+		if err != nil {
+			fmt.Println("failed to list netconfs, err:", err)
+			return
+		}
+		for _, e := range entries {
+			printNetconfEntry(e)
+		}
+		return
+	}
+
+	families := []int{preferredFamily}
+	if preferredFamily == syscall.AF_UNSPEC {
+		families = []int{syscall.AF_INET, syscall.AF_INET6}
+	}
+	for _, family := range families {
+		e, err := ipcli.GetNetconf(family, ifindex)
+		if err != nil {
+			fmt.Println("failed to get netconf, err:", err)
+			return
+		}
+		printNetconfEntry(e)
+	}
+}
+
+func printNetconfEntry(e *ip.NetconfEntry) {
+	var s strings.Builder
+	s.WriteString(familyName(e.Family) + " ")
+	switch e.Ifindex {
+	case ip.NETCONFA_IFINDEX_ALL:
+		s.WriteString("all ")
+	case ip.NETCONFA_IFINDEX_DEFAULT:
+		s.WriteString("default ")
+	default:
+		s.WriteString(ifname(e.Ifindex) + " ")
+	}
+
+	onOff := func(v int) string {
+		if v != 0 {
+			return "on"
+		}
+		return "off"
+	}
+	if e.Forwarding != -1 {
+		s.WriteString(fmt.Sprintf("forwarding %s ", onOff(e.Forwarding)))
+	}
+	if e.RPFilter != -1 {
+		rpFilter := "off"
+		switch e.RPFilter {
+		case 1:
+			rpFilter = "strict"
+		case 2:
+			rpFilter = "loose"
+		}
+		s.WriteString(fmt.Sprintf("rp_filter %s ", rpFilter))
+	}
+	if e.MCForwarding != -1 {
+		s.WriteString(fmt.Sprintf("mc_forwarding %s ", onOff(e.MCForwarding)))
+	}
+	if e.ProxyNeigh != -1 {
+		s.WriteString(fmt.Sprintf("proxy_neigh %s ", onOff(e.ProxyNeigh)))
+	}
+	if e.IgnoreRoutesWithLinkdown != -1 {
+		s.WriteString(fmt.Sprintf("ignore_routes_with_linkdown %s ", onOff(e.IgnoreRoutesWithLinkdown)))
+	}
+	if e.Input != -1 {
+		s.WriteString(fmt.Sprintf("input %s ", onOff(e.Input)))
+	}
+	fmt.Println(s.String())
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	rootCmd.AddCommand(nexthopCmd())
+}
+
+func nexthopCmd() *cobra.Command {
+	nexthopCmd := &cobra.Command{
+		Use:     "nexthop",
+		Aliases: []string{"nex", "next", "nexth", "nextho"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNexthops(args) })
+		},
+	}
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNexthops(args) })
+		},
+	})
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "add",
+		Aliases: []string{"a", "ad"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyNexthop(args, (*ip.Client).AddNexthop) })
+		},
+	})
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "replace",
+		Aliases: []string{"r", "re", "rep", "repl", "repla", "replac"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyNexthop(args, (*ip.Client).ReplaceNexthop) })
+		},
+	})
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "del",
+		Aliases: []string{"d", "de", "delete"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.delNexthop(args) })
+		},
+	})
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "flush",
+		Aliases: []string{"f", "fl", "flu", "flus"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.flushNexthops(args) })
+		},
+	})
+	nexthopCmd.AddCommand(&cobra.Command{
+		Use:     "get",
+		Aliases: []string{"g", "ge"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.getNexthop(args) })
+		},
+	})
+	nexthopCmd.AddCommand(nexthopBucketCmd())
+	return nexthopCmd
+}
+
+func nexthopBucketCmd() *cobra.Command {
+	bucketCmd := &cobra.Command{
+		Use:     "bucket",
+		Aliases: []string{"b", "bu", "buc", "buck", "bucke"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNexthopBuckets(args) })
+		},
+	}
+	bucketCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNexthopBuckets(args) })
+		},
+	})
+	bucketCmd.AddCommand(&cobra.Command{
+		Use:     "get",
+		Aliases: []string{"g", "ge"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.getNexthopBucket(args) })
+		},
+	})
+	return bucketCmd
+}
+
+// parseNexthopFilter parses the selectors of `ip nexthop show|flush`,
+// and the id which makes a get instead of a dump.
+func parseNexthopFilter(args []string) (*ip.NexthopFilter, uint32, error) {
+	var filter ip.NexthopFilter
+	var id uint64
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "id":
+			id, err = p.uint(key, 32)
+		case "dev":
+			filter.Ifindex, err = p.ifindex(key)
+		case "master":
+			filter.Master, err = p.ifindex(key)
+		case "groups":
+			filter.Groups = true
+		case "fdb":
+			filter.FDB = true
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return &filter, uint32(id), nil
+}
+
+func (c *client) listNexthops(args []string) {
+	filter, id, err := parseNexthopFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if id != 0 {
+		c.getNexthop(args)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListNexthops(filter)
+	if err != nil {
+		fmt.Println("failed to list nexthops, err:", err)
+		return
+	}
+
+	for _, e := range entries {
+		printNexthopEntry(e)
+	}
+}
+
+func (c *client) getNexthop(args []string) {
+	_, id, err := parseNexthopFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if id == 0 {
+		fmt.Println("nexthop id is required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	e, err := ipcli.GetNexthop(id)
+	if err != nil {
+		fmt.Println("failed to get nexthop, err:", err)
+		return
+	}
+	printNexthopEntry(e)
+}
+
+func (c *client) flushNexthops(args []string) {
+	filter, _, err := parseNexthopFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	n, err := ipcli.FlushNexthops(filter)
+	if err != nil {
+		fmt.Println("failed to flush nexthops, err:", err)
+		return
+	}
+	if n == 0 {
+		fmt.Println("Nothing to flush")
+	} else {
+		fmt.Printf("Flushed %d nexthops\n", n)
+	}
+}
+
+func (c *client) delNexthop(args []string) {
+	_, id, err := parseNexthopFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if id == 0 {
+		fmt.Println("nexthop id is required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := ipcli.DelNexthop(id); err != nil {
+		fmt.Println("failed to delete nexthop, err:", err)
+	}
+}
+
+func (c *client) modifyNexthop(args []string, fn func(*ip.Client, *ip.NexthopEntry) error) {
+	e, err := parseNexthopArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := fn(ipcli, e); err != nil {
+		fmt.Println("failed to modify nexthop, err:", err)
+	}
+}
+
+// parseNexthopArgs parses the nexthop object of `ip nexthop add`, like
+// `id 1 via 10.0.0.1 dev eth0` or `id 10 group 1/2,3 type resilient`.
+func parseNexthopArgs(args []string) (*ip.NexthopEntry, error) {
+	var e ip.NexthopEntry
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "id":
+			var id uint64
+			id, err = p.uint(key, 32)
+			e.ID = uint32(id)
+		case "via":
+			e.Gateway, err = p.addr(key)
+		case "dev":
+			e.Ifindex, err = p.ifindex(key)
+		case "onlink":
+			e.Flags |= unix.RTNH_F_ONLINK
+		case "blackhole":
+			e.Blackhole = true
+		case "fdb":
+			e.FDB = true
+		case "encap":
+			e.Encap, err = parseEncap(p)
+		case "group":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Group, err = parseNexthopGroup(val)
+			}
+		case "type":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.GroupType, err = ip.ParseNexthopGroupType(val)
+			}
+		case "buckets":
+			var n uint64
+			n, err = p.uint(key, 16)
+			nexthopResilient(&e).Buckets = int(n)
+		case "idle_timer", "unbalanced_timer":
+			var secs uint64
+			secs, err = p.uint(key, 32)
+			timer := time.Duration(secs) * time.Second
+			if key == "idle_timer" {
+				nexthopResilient(&e).IdleTimer = timer
+			} else {
+				nexthopResilient(&e).UnbalancedTimer = timer
+			}
+		case "proto", "protocol":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Protocol, err = ip.ParseRouteProtocol(val)
+			}
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Resilient != nil && e.GroupType != ip.NEXTHOP_GRP_TYPE_RES {
+		return nil, fmt.Errorf("buckets and timers are only for resilient groups")
+	}
+	return &e, nil
+}
+
+// nexthopResilient returns the resilient settings of the nexthop,
+// and creates them at the first use.
+func nexthopResilient(e *ip.NexthopEntry) *ip.NexthopResilient {
+	if e.Resilient == nil {
+		e.Resilient = &ip.NexthopResilient{}
+	}
+	return e.Resilient
+}
+
+// parseNexthopGroup parses the members of a group like `1/2,3`, whose
+// weight follows the id after a comma.
+func parseNexthopGroup(s string) ([]*ip.NexthopGroupMember, error) {
+	var members []*ip.NexthopGroupMember
+	for _, m := range strings.Split(s, "/") {
+		idStr, weightStr := m, "1"
+		if i := strings.IndexByte(m, ','); i >= 0 {
+			idStr, weightStr = m[:i], m[i+1:]
+		}
+		id, err := strconv.ParseUint(idStr, 0, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid nexthop id \"%s\"", idStr)
+		}
+		weight, err := strconv.ParseUint(weightStr, 0, 16)
+		if err != nil || weight == 0 || weight > 256 {
+			return nil, fmt.Errorf("invalid weight \"%s\"", weightStr)
+		}
+		members = append(members, &ip.NexthopGroupMember{
+			ID:     uint32(id),
+			Weight: int(weight),
+		})
+	}
+	return members, nil
+}
+
+// parseNexthopBucketArgs parses the selectors of `ip nexthop bucket`.
+func parseNexthopBucketArgs(args []string) (*ip.NexthopBucketFilter, int, error) {
+	var filter ip.NexthopBucketFilter
+	index := -1
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "id", "nhid":
+			var id uint64
+			id, err = p.uint(key, 32)
+			if key == "id" {
+				filter.ID = uint32(id)
+			} else {
+				filter.NexthopID = uint32(id)
+			}
+		case "index":
+			var n uint64
+			n, err = p.uint(key, 16)
+			index = int(n)
+		case "dev":
+			filter.Ifindex, err = p.ifindex(key)
+		case "master":
+			filter.Master, err = p.ifindex(key)
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return &filter, index, nil
+}
+
+func (c *client) listNexthopBuckets(args []string) {
+	filter, _, err := parseNexthopBucketArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	buckets, err := ipcli.ListNexthopBuckets(filter)
+	if err != nil {
+		fmt.Println("failed to list nexthop buckets, err:", err)
+		return
+	}
+
+	for _, b := range buckets {
+		printNexthopBucket(b)
+	}
+}
+
+func (c *client) getNexthopBucket(args []string) {
+	filter, index, err := parseNexthopBucketArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if filter.ID == 0 || index < 0 {
+		fmt.Println("nexthop group id and bucket index are required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	b, err := ipcli.GetNexthopBucket(filter.ID, index)
+	if err != nil {
+		fmt.Println("failed to get nexthop bucket, err:", err)
+		return
+	}
+	printNexthopBucket(b)
+}
+
+// formatSeconds formats the duration as seconds, like `0.01`.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func printNexthopEntry(e *ip.NexthopEntry) {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("id %d ", e.ID))
+
+	if len(e.Group) != 0 {
+		members := make([]string, 0, len(e.Group))
+		for _, m := range e.Group {
+			if m.Weight != 1 {
+				members = append(members, fmt.Sprintf("%d,%d", m.ID, m.Weight))
+			} else {
+				members = append(members, fmt.Sprintf("%d", m.ID))
+			}
+		}
+		s.WriteString(fmt.Sprintf("group %s ", strings.Join(members, "/")))
+		if e.GroupType != ip.NEXTHOP_GRP_TYPE_MPATH {
+			s.WriteString(fmt.Sprintf("type %s ", e.GroupType))
+		}
+	}
+
+	if r := e.Resilient; r != nil {
+		s.WriteString(fmt.Sprintf("buckets %d idle_timer %s unbalanced_timer %s unbalanced_time %s ",
+			r.Buckets, formatSeconds(r.IdleTimer), formatSeconds(r.UnbalancedTimer),
+			formatSeconds(r.UnbalancedTime)))
+	}
+
+	if e.Encap != nil {
+		s.WriteString(formatEncap(e.Encap))
+	}
+
+	if e.Blackhole {
+		s.WriteString("blackhole ")
+	}
+
+	if e.Gateway != nil {
+		s.WriteString(fmt.Sprintf("via %s ", e.Gateway))
+	}
+
+	if e.Ifindex != 0 {
+		s.WriteString(fmt.Sprintf("dev %s ", ifname(e.Ifindex)))
+	}
+
+	if e.Scope != unix.RT_SCOPE_UNIVERSE {
+		s.WriteString(fmt.Sprintf("scope %s ", e.Scope))
+	}
+
+	if flags := e.Flags.String(); flags != "" {
+		s.WriteString(flags + " ")
+	}
+
+	if e.FDB {
+		s.WriteString("fdb ")
+	}
+
+	if e.Protocol != unix.RTPROT_UNSPEC {
+		s.WriteString(fmt.Sprintf("proto %s ", e.Protocol))
+	}
+
+	fmt.Println(s.String())
+}
+
+func printNexthopBucket(b *ip.NexthopBucket) {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("id %d index %d idle_time %s nhid %d ",
+		b.ID, b.Index, formatSeconds(b.IdleTime), b.NexthopID))
+	if flags := b.Flags.String(); flags != "" {
+		s.WriteString(flags + " ")
+	}
+	fmt.Println(s.String())
+}
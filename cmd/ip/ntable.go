@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(ntableCmd())
+}
+
+func ntableCmd() *cobra.Command {
+	ntableCmd := &cobra.Command{
+		Use:     "ntable",
+		Aliases: []string{"nt", "ntb", "ntbl"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNeighTables(args) })
+		},
+	}
+	ntableCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listNeighTables(args) })
+		},
+	})
+	ntableCmd.AddCommand(&cobra.Command{
+		Use:     "change",
+		Aliases: []string{"c", "ch", "cha", "chan", "chang"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.changeNeighTable(args) })
+		},
+	})
+	return ntableCmd
+}
+
+func (c *client) listNeighTables(args []string) {
+	var name string
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "dev":
+			ifindex, err = p.ifindex(key)
+		case "name":
+			name, err = p.value(key)
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	tables, err := ipcli.ListNeighTables(preferredFamily)
+	if err != nil {
+		fmt.Println("failed to list neighbour tables, err:", err)
+		return
+	}
+
+	for _, t := range tables {
+		if name != "" && t.Name != name {
+			continue
+		}
+		if ifindex != 0 && (t.Parms == nil || t.Parms.Ifindex != ifindex) {
+			continue
+		}
+		printNeighTable(t)
+	}
+}
+
+func (c *client) changeNeighTable(args []string) {
+	var ch ip.NeighTableChange
+	ch.Family = preferredFamily
+
+	u32 := func(v uint64) *uint32 {
+		n := uint32(v)
+		return &n
+	}
+	msecs := func(v uint64) *time.Duration {
+		d := time.Duration(v) * time.Millisecond
+		return &d
+	}
+	fields := map[string]struct {
+		count **uint32
+		msecs **time.Duration
+	}{
+		"thresh1":                {count: &ch.Thresh1},
+		"thresh2":                {count: &ch.Thresh2},
+		"thresh3":                {count: &ch.Thresh3},
+		"gc_int":                 {msecs: &ch.GCInterval},
+		"base_reachable":         {msecs: &ch.BaseReachableTime},
+		"retrans":                {msecs: &ch.RetransTime},
+		"gc_stale":               {msecs: &ch.GCStaleTime},
+		"delay_probe":            {msecs: &ch.DelayProbeTime},
+		"queue":                  {count: &ch.QueueLen},
+		"queue_len_bytes":        {count: &ch.QueueLenBytes},
+		"app_probes":             {count: &ch.AppProbes},
+		"ucast_probes":           {count: &ch.UcastProbes},
+		"mcast_probes":           {count: &ch.McastProbes},
+		"mcast_reprobes":         {count: &ch.McastReprobes},
+		"anycast_delay":          {msecs: &ch.AnycastDelay},
+		"proxy_delay":            {msecs: &ch.ProxyDelay},
+		"proxy_queue":            {count: &ch.ProxyQueueLen},
+		"locktime":               {msecs: &ch.LockTime},
+		"interval_probe_time_ms": {msecs: &ch.IntervalProbeTime},
+	}
+
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "name":
+			ch.Name, err = p.value(key)
+		case "dev":
+			ch.Ifindex, err = p.ifindex(key)
+		default:
+			field, ok := fields[key]
+			if !ok {
+				err = fmt.Errorf("unknown argument \"%s\"", key)
+				break
+			}
+			var val uint64
+			if field.count != nil {
+				if val, err = p.uint(key, 32); err == nil {
+					*field.count = u32(val)
+				}
+			} else {
+				if val, err = p.uint(key, 64); err == nil {
+					*field.msecs = msecs(val)
+				}
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if ch.Name == "" {
+		fmt.Println("Not enough information: \"name\" argument is required.")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := ipcli.ChangeNeighTable(&ch); err != nil {
+		fmt.Println("failed to change neighbour table, err:", err)
+	}
+}
+
+// formatMsecs formats the duration in milliseconds.
+func formatMsecs(d time.Duration) string {
+	return fmt.Sprintf("%d", d.Milliseconds())
+}
+
+// writeNeighTableItems writes the items which are given, like
+// `    refcnt 1 reachable 44620 `, in a line.
+func writeNeighTableItems(s *strings.Builder, indent string, items [][2]string) {
+	line := indent
+	for _, item := range items {
+		if item[1] != "" {
+			line += item[0] + " " + item[1] + " "
+		}
+	}
+	if line != indent {
+		s.WriteString(line + "\n")
+	}
+}
+
+func printNeighTable(t *ip.NeighTable) {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("%s %s \n", familyName(t.Family), t.Name))
+
+	count := func(n int) string {
+		if n == -1 {
+			return ""
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	msecs := func(d time.Duration) string {
+		if d == -1 {
+			return ""
+		}
+		return formatMsecs(d)
+	}
+
+	if t.Parms != nil && t.Parms.Ifindex != 0 {
+		s.WriteString(fmt.Sprintf("    dev %s \n", ifname(t.Parms.Ifindex)))
+	}
+
+	writeNeighTableItems(&s, "    ", [][2]string{
+		{"thresh1", count(t.Thresh1)},
+		{"thresh2", count(t.Thresh2)},
+		{"thresh3", count(t.Thresh3)},
+		{"gc_int", msecs(t.GCInterval)},
+	})
+
+	if cfg := t.Config; cfg != nil && showStats > 0 {
+		// the times are in seconds like iproute2.
+		const layout = "2006-01-02 15:04:05"
+		now := time.Now().Truncate(time.Second)
+		s.WriteString(fmt.Sprintf("    config key_len %d entry_size %d entries %d \n",
+			cfg.KeyLen, cfg.EntrySize, cfg.Entries))
+		s.WriteString(fmt.Sprintf("        last_flush %s last_rand %s \n",
+			now.Add(-cfg.LastFlush.Truncate(time.Second)).Format(layout),
+			now.Add(-cfg.LastRand.Truncate(time.Second)).Format(layout)))
+		s.WriteString(fmt.Sprintf("        hash_rnd %d hash_mask %08x hash_chain_gc %d proxy_qlen %d \n",
+			cfg.HashRnd, cfg.HashMask, cfg.HashChainGC, cfg.ProxyQueueLen))
+	}
+
+	if p := t.Parms; p != nil {
+		writeNeighTableItems(&s, "    ", [][2]string{
+			{"refcnt", count(p.RefCount)},
+			{"reachable", msecs(p.ReachableTime)},
+			{"base_reachable", msecs(p.BaseReachableTime)},
+			{"retrans", msecs(p.RetransTime)},
+		})
+		writeNeighTableItems(&s, "    ", [][2]string{
+			{"gc_stale", msecs(p.GCStaleTime)},
+			{"delay_probe", msecs(p.DelayProbeTime)},
+			{"queue", count(p.QueueLen)},
+			{"queue_len_bytes", count(p.QueueLenBytes)},
+		})
+		writeNeighTableItems(&s, "    ", [][2]string{
+			{"app_probes", count(p.AppProbes)},
+			{"ucast_probes", count(p.UcastProbes)},
+			{"mcast_probes", count(p.McastProbes)},
+			{"mcast_reprobes", count(p.McastReprobes)},
+		})
+		writeNeighTableItems(&s, "    ", [][2]string{
+			{"anycast_delay", msecs(p.AnycastDelay)},
+			{"proxy_delay", msecs(p.ProxyDelay)},
+			{"proxy_queue", count(p.ProxyQueueLen)},
+			{"locktime", msecs(p.LockTime)},
+			{"interval_probe_time_ms", msecs(p.IntervalProbeTime)},
+		})
+	}
+
+	if st := t.Stats; st != nil && showStats > 0 {
+		s.WriteString(fmt.Sprintf("    stats allocs %d destroys %d hash_grows %d \n",
+			st.Allocs, st.Destroys, st.HashGrows))
+		s.WriteString(fmt.Sprintf("    res_failed %d lookups %d hits %d \n",
+			st.ResFailed, st.Lookups, st.Hits))
+		s.WriteString(fmt.Sprintf("    rcv_probes_mcast %d rcv_probes_ucast %d \n",
+			st.RcvProbesMcast, st.RcvProbesUcast))
+		s.WriteString(fmt.Sprintf("    periodic_gc_runs %d forced_gc_runs %d \n",
+			st.PeriodicGCRuns, st.ForcedGCRuns))
+		s.WriteString(fmt.Sprintf("    table_fulls %d \n", st.TableFulls))
+	}
+	fmt.Println(s.String())
+}
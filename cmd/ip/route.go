@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"net"
+	"os"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Asphaltt/go-iproute2/ip"
 	"github.com/spf13/cobra"
@@ -19,40 +23,870 @@ func routeCmd() *cobra.Command {
 		Use:     "route",
 		Aliases: []string{"r", "ro", "rou", "rout"},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli.runCmd(cli.listRoutes)
+			cli.runCmd(func() { cli.listRoutes(args) })
 		},
 	}
 	routeCmd.AddCommand(&cobra.Command{
 		Use:     "list",
 		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli.runCmd(cli.listRoutes)
+			cli.runCmd(func() { cli.listRoutes(args) })
 		},
 	})
+	routeCmd.AddCommand(&cobra.Command{
+		Use:     "flush",
+		Aliases: []string{"f", "fl", "flu", "flus"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.flushRoutes(args) })
+		},
+	})
+	routeCmd.AddCommand(&cobra.Command{
+		Use:     "save",
+		Aliases: []string{"sa", "sav"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.saveRoutes(args) })
+		},
+	})
+	routeCmd.AddCommand(&cobra.Command{
+		Use:     "restore",
+		Aliases: []string{"rest", "resto", "restor"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.restoreRoutes)
+		},
+	})
+	routeCmd.AddCommand(&cobra.Command{
+		Use: "showdump",
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(showRouteDump)
+		},
+	})
+	routeCmd.AddCommand(&cobra.Command{
+		Use:     "get",
+		Aliases: []string{"g", "ge"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.getRoute(args) })
+		},
+	})
+
+	modifyCmds := []struct {
+		use     string
+		aliases []string
+		fn      func(*ip.Client, *ip.RouteEntry) error
+	}{
+		{"add", []string{"a", "ad"}, (*ip.Client).AddRoute},
+		{"del", []string{"d", "de", "delete"}, (*ip.Client).DelRoute},
+		{"replace", []string{"repl"}, (*ip.Client).ReplaceRoute},
+		{"change", []string{"chg"}, (*ip.Client).ChangeRoute},
+		{"append", []string{"app", "appe", "appen"}, (*ip.Client).AppendRoute},
+		{"prepend", []string{"prep", "prepe", "prepen"}, (*ip.Client).PrependRoute},
+	}
+	for _, m := range modifyCmds {
+		m := m
+		routeCmd.AddCommand(&cobra.Command{
+			Use:     m.use,
+			Aliases: m.aliases,
+			Run: func(cmd *cobra.Command, args []string) {
+				cli.runCmd(func() { cli.modifyRoute(args, m.use == "del", m.fn) })
+			},
+		})
+	}
 	return routeCmd
 }
 
-func (c *client) listRoutes() {
+func (c *client) listRoutes(args []string) {
+	filter, err := parseRouteFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	ipcli := ip.NewWithConn(c.conn)
-	entries, err := ipcli.ListRoutes()
+	entries, err := ipcli.ListRoutesWithFilter(filter)
 	if err != nil {
 		fmt.Println("failed to list route entries, err:", err)
 		return
 	}
 
 	for _, e := range entries {
-		printRouteEntry(e)
+		printRouteEntry(e, filter)
+	}
+}
+
+func (c *client) flushRoutes(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Flush requires arguments.")
+		return
+	}
+	filter, err := parseRouteFilter(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	n, err := ipcli.FlushRoutes(filter)
+	if err != nil {
+		fmt.Println("failed to flush routes, err:", err)
+		return
+	}
+	if showStats > 0 {
+		fmt.Printf("*** Flush is complete, %d routes deleted ***\n", n)
+	}
+}
+
+// isTerminal reports whether the file is a terminal, which the binary
+// route dump is not written to or read from.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *client) saveRoutes(args []string) {
+	if isTerminal(os.Stdout) {
+		fmt.Println("Not sending a binary stream to stdout")
+		return
+	}
+	filter, err := parseRouteFilter(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if _, err := ipcli.SaveRoutes(os.Stdout, filter); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to save routes, err:", err)
+	}
+}
+
+func (c *client) restoreRoutes() {
+	if isTerminal(os.Stdin) {
+		fmt.Println("Can't restore route dump from a terminal")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if _, err := ipcli.RestoreRoutes(os.Stdin); err != nil {
+		fmt.Println("failed to restore routes, err:", err)
+	}
+}
+
+func showRouteDump() {
+	if isTerminal(os.Stdin) {
+		fmt.Println("Can't restore route dump from a terminal")
+		return
+	}
+
+	entries, err := ip.ParseRouteDump(os.Stdin)
+	if err != nil {
+		fmt.Println("failed to show route dump, err:", err)
+		return
+	}
+	for _, e := range entries {
+		printRouteEntry(e, nil)
+	}
+}
+
+// parseRouteFilter parses the route selectors, like
+// `table all proto 186 root 10.0.0.0/8`. The family is IPv4 unless it's
+// given by the options or the prefix, or all tables are selected.
+func parseRouteFilter(args []string) (*ip.RouteFilter, error) {
+	var f ip.RouteFilter
+	f.Family = preferredFamily
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "table":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			switch val {
+			case "all":
+				f.AllTables = true
+			case "cache":
+				f.Cloned = true
+			default:
+				f.Table, err = ip.ParseRouteTable(val)
+			}
+		case "cache", "cloned":
+			f.Cloned = true
+		case "proto", "protocol":
+			var val string
+			if val, err = p.value(key); err == nil {
+				f.Protocol, err = ip.ParseRouteProtocol(val)
+			}
+		case "type":
+			var val string
+			if val, err = p.value(key); err == nil {
+				f.Type, err = ip.ParseRouteType(val)
+			}
+		case "scope":
+			var val string
+			if val, err = p.value(key); err == nil {
+				var scope ip.RouteScope
+				scope, err = ip.ParseRouteScope(val)
+				f.Scope = &scope
+			}
+		case "dev", "oif":
+			f.OutIfindex, err = p.ifindex(key)
+		case "iif":
+			f.InIfindex, err = p.ifindex(key)
+		case "via":
+			var via *ip.RouteVia
+			if f.Gateway, via, err = parseVia(p, f.Family); via != nil {
+				f.Gateway = via.Addr
+			}
+		case "src":
+			f.PrefSrc, err = p.addr(key)
+		default:
+			if key == "to" {
+				if key, err = p.value(key); err != nil {
+					break
+				}
+			}
+			if mode, err := ip.ParseRoutePrefixMode(key); err == nil {
+				f.DstMode = mode
+				if key, err = p.value(key); err != nil {
+					return nil, err
+				}
+			}
+			f.Dst, f.DstLen, err = parsePrefix(key)
+			if err == nil && f.Dst == nil {
+				// the default route of the family.
+				f.Dst = net.IPv4zero.To4()
+				if f.Family == syscall.AF_INET6 {
+					f.Dst = net.IPv6zero
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Family == syscall.AF_UNSPEC && f.Dst != nil {
+		f.Family = syscall.AF_INET6
+		if f.Dst.To4() != nil {
+			f.Family = syscall.AF_INET
+		}
+	}
+	if f.Family == syscall.AF_UNSPEC && !f.AllTables {
+		f.Family = syscall.AF_INET
+	}
+	return &f, nil
+}
+
+func (c *client) modifyRoute(args []string, del bool, fn func(*ip.Client, *ip.RouteEntry) error) {
+	e, err := parseRouteArgs(args, del)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := fn(ipcli, e); err != nil {
+		fmt.Println("failed to modify route, err:", err)
+	}
+}
+
+func (c *client) getRoute(args []string) {
+	var dst net.IP
+	var opts ip.RouteGetOptions
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "from":
+			var bits int
+			opts.From, bits, err = p.prefix(key)
+			if err == nil && (opts.From == nil || bits != len(opts.From)*8) {
+				err = fmt.Errorf("invalid from address")
+			}
+		case "iif":
+			opts.InIfindex, err = p.ifindex(key)
+		case "oif", "dev":
+			opts.OutIfindex, err = p.ifindex(key)
+		case "vrf":
+			opts.VRF, err = p.ifindex(key)
+		case "tos", "dsfield":
+			opts.Tos, err = p.dsfield(key)
+		case "mark":
+			var mark uint64
+			mark, err = p.uint(key, 32)
+			opts.Mark = uint32(mark)
+		case "uid":
+			var uid uint64
+			uid, err = p.uint(key, 32)
+			u := uint32(uid)
+			opts.UID = &u
+		case "ipproto":
+			var val string
+			if val, err = p.value(key); err == nil {
+				opts.IPProto, err = parseIPProto(val)
+			}
+		case "sport", "dport":
+			var port uint64
+			port, err = p.uint(key, 16)
+			if key == "sport" {
+				opts.Sport = uint16(port)
+			} else {
+				opts.Dport = uint16(port)
+			}
+		case "fibmatch":
+			opts.FibMatch = true
+		case "connected":
+			opts.Connected = true
+		default:
+			if key == "to" {
+				if key, err = p.value(key); err != nil {
+					break
+				}
+			}
+			var bits int
+			dst, bits, err = parsePrefix(key)
+			if err == nil && (dst == nil || bits != len(dst)*8) {
+				err = fmt.Errorf("invalid destination \"%s\"", key)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if dst == nil {
+		fmt.Println("need at least a destination address")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	e, err := ipcli.GetRoute(dst, &opts)
+	if err != nil {
+		fmt.Println("failed to get route, err:", err)
+		return
+	}
+	printRouteEntry(e, nil)
+}
+
+// parseIPProto parses the IP protocol from its name or number.
+func parseIPProto(s string) (int, error) {
+	protos := map[string]int{
+		"icmp":      unix.IPPROTO_ICMP,
+		"tcp":       unix.IPPROTO_TCP,
+		"udp":       unix.IPPROTO_UDP,
+		"dccp":      unix.IPPROTO_DCCP,
+		"icmpv6":    unix.IPPROTO_ICMPV6,
+		"ipv6-icmp": unix.IPPROTO_ICMPV6,
+		"sctp":      unix.IPPROTO_SCTP,
+	}
+	if proto, ok := protos[s]; ok {
+		return proto, nil
+	}
+	proto, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ipproto \"%s\"", s)
+	}
+	return int(proto), nil
+}
+
+// ipProtoName gets the name of the IP protocol like /etc/protocols.
+func ipProtoName(proto int) string {
+	switch proto {
+	case unix.IPPROTO_ICMP:
+		return "icmp"
+	case unix.IPPROTO_TCP:
+		return "tcp"
+	case unix.IPPROTO_UDP:
+		return "udp"
+	case unix.IPPROTO_DCCP:
+		return "dccp"
+	case unix.IPPROTO_ICMPV6:
+		return "ipv6-icmp"
+	case unix.IPPROTO_SCTP:
+		return "sctp"
+	}
+	return strconv.Itoa(proto)
+}
+
+// parseRouteArgs parses the route from the arguments, and applies the
+// same defaults as iproute2.
+func parseRouteArgs(args []string, del bool) (*ip.RouteEntry, error) {
+	var e ip.RouteEntry
+	e.Family = preferredFamily
+	if !del {
+		e.Protocol = unix.RTPROT_BOOT
+		e.Type = unix.RTN_UNICAST
+	}
+	mpls := e.Family == unix.AF_MPLS
+
+	dstOk, scopeOk, tableOk := false, false, false
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "via":
+			e.Gateway, e.Via, err = parseVia(p, e.Family)
+		case "as":
+			e.NewDst, err = parseNewDst(p)
+		case "ttl-propagate":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.TTLProp, err = ip.ParseMPLSTTLPropagate(val)
+			}
+		case "src":
+			e.PrefSrc, err = p.addr(key)
+		case "from":
+			e.Saddr, e.SrcLen, err = p.prefix(key)
+		case "tos", "dsfield":
+			e.Tos, err = p.dsfield(key)
+		case "realm", "realms":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Realms, err = parseRealms(val)
+			}
+		case "table":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Table, err = ip.ParseRouteTable(val)
+				tableOk = true
+			}
+		case "proto", "protocol":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Protocol, err = ip.ParseRouteProtocol(val)
+			}
+		case "scope":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Scope, err = ip.ParseRouteScope(val)
+				scopeOk = true
+			}
+		case "metric", "priority", "preference":
+			var metric uint64
+			metric, err = p.uint(key, 32)
+			e.Priority = int(metric)
+		case "dev", "oif":
+			e.OutIfindex, err = p.ifindex(key)
+		case "iif":
+			e.InIfindex, err = p.ifindex(key)
+		case "pref":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Pref, err = ip.ParseRoutePref(val)
+			}
+		case "expires":
+			var expires uint64
+			expires, err = p.uint(key, 32)
+			e.Expires = int(expires)
+		case "onlink":
+			e.Flags |= unix.RTNH_F_ONLINK
+		case "encap":
+			e.Encap, err = parseEncap(p)
+		case "nhid":
+			var id uint64
+			id, err = p.uint(key, 32)
+			e.NexthopID = uint32(id)
+		case "nexthop":
+			// the nexthops take the rest of the arguments.
+			e.MultiPath, err = parseNextHops(p, e.Family)
+		default:
+			if rtax, ok := routeMetricNames[key]; ok {
+				if e.Metrics == nil {
+					e.Metrics = &ip.RouteMetrics{}
+				}
+				err = parseRouteMetric(p, key, rtax, e.Metrics)
+				break
+			}
+
+			if key == "to" {
+				if key, err = p.value(key); err != nil {
+					break
+				}
+			}
+			if typ, err := ip.ParseRouteType(key); err == nil && !dstOk && p.more() {
+				e.Type = typ
+				if key, err = p.value(key); err != nil {
+					return nil, err
+				}
+			}
+			if dstOk {
+				return nil, fmt.Errorf("duplicate destination \"%s\"", key)
+			}
+			dstOk = true
+			if mpls {
+				var label uint64
+				if label, err = strconv.ParseUint(key, 0, 20); err != nil {
+					return nil, fmt.Errorf("invalid mpls label \"%s\"", key)
+				}
+				e.Label = int(label)
+				break
+			}
+			e.Daddr, e.DstLen, err = parsePrefix(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !dstOk {
+		return nil, fmt.Errorf("need at least a destination address")
+	}
+
+	switch int(e.Type) {
+	case unix.RTN_LOCAL, unix.RTN_BROADCAST, unix.RTN_NAT, unix.RTN_ANYCAST:
+		if !tableOk {
+			e.Table = unix.RT_TABLE_LOCAL
+		}
+	}
+
+	if !scopeOk {
+		switch int(e.Type) {
+		case unix.RTN_LOCAL, unix.RTN_NAT:
+			e.Scope = unix.RT_SCOPE_HOST
+		case unix.RTN_BROADCAST, unix.RTN_MULTICAST, unix.RTN_ANYCAST:
+			e.Scope = unix.RT_SCOPE_LINK
+		case unix.RTN_UNICAST, unix.RTN_UNSPEC:
+			if mpls {
+				e.Scope = unix.RT_SCOPE_UNIVERSE
+			} else if del {
+				e.Scope = unix.RT_SCOPE_NOWHERE
+			} else if e.Gateway == nil && e.Via == nil && len(e.MultiPath) == 0 &&
+				e.NexthopID == 0 {
+				e.Scope = unix.RT_SCOPE_LINK
+			}
+		}
+	}
+	return &e, nil
+}
+
+// routeCacheFlagNames is the names of the RTCF_* flags of the IPv4 cloned
+// routes, which are in the upper 16 bits of the route flags.
+var routeCacheFlagNames = []struct {
+	flag int
+	name string
+}{
+	{0x80000000, "local"},
+	{0x40000000, "reject"},
+	{0x20000000, "mc"},
+	{0x10000000, "brd"},
+	{0x08000000, "dst-nat"},
+	{0x00800000, "src-nat"},
+	{0x00400000, "masq"},
+	{0x00020000, "dst-direct"},
+	{0x04000000, "src-direct"},
+	{0x00040000, "redirected"},
+	{0x01000000, "redirect"},
+	{0x00200000, "fastroute"},
+	{0x00010000, "notify"},
+	{0x00080000, "proxy"},
+}
+
+// formatRouteCacheFlags formats the cache flags like `<local,brd> `.
+func formatRouteCacheFlags(flags ip.RouteFlags) string {
+	f := int(flags) &^ 0xffff
+	if f == 0 {
+		return ""
+	}
+
+	var names []string
+	for _, n := range routeCacheFlagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("%x", f))
+	}
+	return "<" + strings.Join(names, ",") + "> "
+}
+
+// formatRouteCacheInfo formats the cache information, and the usage is
+// only shown with -s.
+func formatRouteCacheInfo(ci *ip.RouteCacheInfo) string {
+	var s strings.Builder
+	if ci.Expires != 0 {
+		s.WriteString(fmt.Sprintf("expires %dsec ", int(ci.Expires/time.Second)))
+	}
+	if ci.Error != 0 {
+		s.WriteString(fmt.Sprintf("error %d ", ci.Error))
+	}
+	if showStats > 0 {
+		if ci.Users != 0 {
+			s.WriteString(fmt.Sprintf("users %d ", ci.Users))
+		}
+		if ci.Used != 0 {
+			s.WriteString(fmt.Sprintf("used %d ", ci.Used))
+		}
+		if ci.LastUse != 0 {
+			s.WriteString(fmt.Sprintf("age %dsec ", int(ci.LastUse/time.Second)))
+		}
+	}
+	if ci.IPID != 0 {
+		s.WriteString(fmt.Sprintf("ipid 0x%04x ", ci.IPID))
+	}
+	if ci.TS != 0 || ci.TSAge != 0 {
+		s.WriteString(fmt.Sprintf("ts 0x%x tsage %dsec ", ci.TS, ci.TSAge))
+	}
+	return s.String()
+}
+
+// routeMetricNames maps the route metric keywords to their RTAX_*.
+var routeMetricNames = map[string]int{
+	"mtu":                unix.RTAX_MTU,
+	"advmss":             unix.RTAX_ADVMSS,
+	"window":             unix.RTAX_WINDOW,
+	"rtt":                unix.RTAX_RTT,
+	"rttvar":             unix.RTAX_RTTVAR,
+	"ssthresh":           unix.RTAX_SSTHRESH,
+	"cwnd":               unix.RTAX_CWND,
+	"initcwnd":           unix.RTAX_INITCWND,
+	"initrwnd":           unix.RTAX_INITRWND,
+	"reordering":         unix.RTAX_REORDERING,
+	"hoplimit":           unix.RTAX_HOPLIMIT,
+	"features":           unix.RTAX_FEATURES,
+	"rto_min":            unix.RTAX_RTO_MIN,
+	"quickack":           unix.RTAX_QUICKACK,
+	"congctl":            unix.RTAX_CC_ALGO,
+	"fastopen_no_cookie": unix.RTAX_FASTOPEN_NO_COOKIE,
+}
+
+// parseRouteMetric parses the value of the route metric keyword,
+// like `mtu lock 1400`, `rtt 20ms` or `features ecn`.
+func parseRouteMetric(p *argParser, key string, rtax int, m *ip.RouteMetrics) error {
+	val, err := p.value(key)
+	if err != nil {
+		return err
+	}
+	if val == "lock" {
+		m.Lock |= 1 << uint(rtax)
+		if val, err = p.value(key); err != nil {
+			return err
+		}
+	}
+
+	switch rtax {
+	case unix.RTAX_CC_ALGO:
+		m.CCAlgo = val
+		return nil
+	case unix.RTAX_FEATURES:
+		switch val {
+		case "ecn":
+			m.Features |= unix.RTAX_FEATURE_ECN
+		case "tcp_usec_ts":
+			m.Features |= ip.RTAX_FEATURE_TCP_USEC_TS
+		default:
+			return fmt.Errorf("invalid features \"%s\"", val)
+		}
+		return nil
+	case unix.RTAX_RTT, unix.RTAX_RTTVAR, unix.RTAX_RTO_MIN:
+		// a number without unit is in the kernel unit,
+		// like iproute2 does.
+		units := map[int]time.Duration{
+			unix.RTAX_RTT:     time.Millisecond / 8,
+			unix.RTAX_RTTVAR:  time.Millisecond / 4,
+			unix.RTAX_RTO_MIN: time.Millisecond,
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			n, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return fmt.Errorf("invalid %s \"%s\"", key, val)
+			}
+			d = time.Duration(n) * units[rtax]
+		}
+		switch rtax {
+		case unix.RTAX_RTT:
+			m.RTT = d
+		case unix.RTAX_RTTVAR:
+			m.RTTVar = d
+		default:
+			m.RTOMin = d
+		}
+		return nil
+	}
+
+	n, err := strconv.ParseUint(val, 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid %s \"%s\"", key, val)
+	}
+	v := uint32(n)
+	switch rtax {
+	case unix.RTAX_MTU:
+		m.MTU = v
+	case unix.RTAX_ADVMSS:
+		m.AdvMSS = v
+	case unix.RTAX_WINDOW:
+		m.Window = v
+	case unix.RTAX_SSTHRESH:
+		m.SSThresh = v
+	case unix.RTAX_CWND:
+		m.CWnd = v
+	case unix.RTAX_INITCWND:
+		m.InitCWnd = v
+	case unix.RTAX_INITRWND:
+		m.InitRWnd = v
+	case unix.RTAX_REORDERING:
+		m.Reordering = v
+	case unix.RTAX_HOPLIMIT:
+		m.HopLimit = v
+	case unix.RTAX_QUICKACK:
+		m.QuickAck = v
+	case unix.RTAX_FASTOPEN_NO_COOKIE:
+		m.FastopenNoCookie = v
+	}
+	return nil
+}
+
+// formatRouteMetrics formats the route metrics like iproute2.
+func formatRouteMetrics(m *ip.RouteMetrics) string {
+	formatUint := func(v uint32) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	formatDuration := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+
+	// the metrics are in the order of RTAX_*.
+	metrics := []struct {
+		rtax int
+		name string
+		val  string
+	}{
+		{unix.RTAX_MTU, "mtu", formatUint(m.MTU)},
+		{unix.RTAX_WINDOW, "window", formatUint(m.Window)},
+		{unix.RTAX_RTT, "rtt", formatDuration(m.RTT)},
+		{unix.RTAX_RTTVAR, "rttvar", formatDuration(m.RTTVar)},
+		{unix.RTAX_SSTHRESH, "ssthresh", formatUint(m.SSThresh)},
+		{unix.RTAX_CWND, "cwnd", formatUint(m.CWnd)},
+		{unix.RTAX_ADVMSS, "advmss", formatUint(m.AdvMSS)},
+		{unix.RTAX_REORDERING, "reordering", formatUint(m.Reordering)},
+		{unix.RTAX_HOPLIMIT, "hoplimit", formatUint(m.HopLimit)},
+		{unix.RTAX_INITCWND, "initcwnd", formatUint(m.InitCWnd)},
+		{unix.RTAX_FEATURES, "features", m.Features.String()},
+		{unix.RTAX_RTO_MIN, "rto_min", formatDuration(m.RTOMin)},
+		{unix.RTAX_INITRWND, "initrwnd", formatUint(m.InitRWnd)},
+		{unix.RTAX_QUICKACK, "quickack", formatUint(m.QuickAck)},
+		{unix.RTAX_CC_ALGO, "congctl", m.CCAlgo},
+		{unix.RTAX_FASTOPEN_NO_COOKIE, "fastopen_no_cookie", formatUint(m.FastopenNoCookie)},
 	}
+
+	var s strings.Builder
+	for _, metric := range metrics {
+		if metric.val == "" {
+			continue
+		}
+		s.WriteString(metric.name + " ")
+		if m.Locked(metric.rtax) {
+			s.WriteString("lock ")
+		}
+		s.WriteString(metric.val + " ")
+	}
+	return s.String()
+}
+
+// parseVia parses the gateway, which is a RouteVia if its family is
+// given, like `via inet6 fe80::1`, or the route is an MPLS route.
+func parseVia(p *argParser, routeFamily int) (net.IP, *ip.RouteVia, error) {
+	if p.more() {
+		family := 0
+		switch p.peek() {
+		case "inet":
+			family = syscall.AF_INET
+		case "inet6":
+			family = syscall.AF_INET6
+		}
+		if family != 0 {
+			key := p.next()
+			addr, err := p.addr(key)
+			if err != nil {
+				return nil, nil, err
+			}
+			return nil, &ip.RouteVia{Family: family, Addr: addr}, nil
+		}
+	}
+	addr, err := p.addr("via")
+	if err != nil || routeFamily != unix.AF_MPLS {
+		return addr, nil, err
+	}
+	family := syscall.AF_INET6
+	if ip4 := addr.To4(); ip4 != nil {
+		family, addr = syscall.AF_INET, ip4
+	}
+	return nil, &ip.RouteVia{Family: family, Addr: addr}, nil
+}
+
+// parseNewDst parses the labels to swap the MPLS label to, like
+// `as to 200/300`.
+func parseNewDst(p *argParser) ([]uint32, error) {
+	if p.more() && p.peek() == "to" {
+		p.next()
+	}
+	val, err := p.value("as")
+	if err != nil {
+		return nil, err
+	}
+	return parseMPLSLabels(val)
+}
+
+// parseNextHops parses the nexthops of a multipath route, like
+// `nexthop via 10.0.0.1 dev eth0 weight 2 nexthop via 10.0.1.1`.
+func parseNextHops(p *argParser, family int) ([]*ip.RouteNextHop, error) {
+	var nhs []*ip.RouteNextHop
+	nh := &ip.RouteNextHop{}
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "nexthop":
+			nhs = append(nhs, nh)
+			nh = &ip.RouteNextHop{}
+		case "via":
+			nh.Gateway, nh.Via, err = parseVia(p, family)
+		case "as":
+			nh.NewDst, err = parseNewDst(p)
+		case "dev":
+			nh.Ifindex, err = p.ifindex(key)
+		case "weight":
+			var weight uint64
+			if weight, err = p.uint(key, 32); err == nil && (weight == 0 || weight > 256) {
+				err = fmt.Errorf("invalid weight \"%d\"", weight)
+			}
+			nh.Weight = int(weight)
+		case "onlink":
+			nh.Flags |= unix.RTNH_F_ONLINK
+		case "encap":
+			nh.Encap, err = parseEncap(p)
+		case "realm", "realms":
+			var val string
+			if val, err = p.value(key); err == nil {
+				nh.Realms, err = parseRealms(val)
+			}
+		default:
+			err = fmt.Errorf("unknown nexthop argument \"%s\"", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(nhs, nh), nil
 }
 
-func printRouteEntry(e *ip.RouteEntry) {
+// printRouteEntry prints the route like iproute2, which omits the
+// attributes selected by the filter. The filter is nil for a route not
+// from listing.
+func printRouteEntry(e *ip.RouteEntry, f *ip.RouteFilter) {
+	if f == nil {
+		f = &ip.RouteFilter{AllTables: true}
+	}
 	table := e.Table
 	if table == -1 {
 		table = e.TableID
 	}
-	// if int(table) != unix.RT_TABLE_MAIN {
-	// 	continue
-	// }
 
 	var s strings.Builder
 
@@ -60,8 +894,10 @@ func printRouteEntry(e *ip.RouteEntry) {
 		s.WriteString(fmt.Sprintf("%s ", e.Type))
 	}
 
-	if e.Daddr != nil {
-		if e.DstLen != 32 { // IPv4 地址有 32 个比特
+	if e.Family == unix.AF_MPLS {
+		s.WriteString(fmt.Sprintf("%d ", e.Label))
+	} else if e.Daddr != nil {
+		if e.DstLen != len(e.Daddr)*8 {
 			s.WriteString(fmt.Sprintf("%s/%d ", e.Daddr, e.DstLen))
 		} else {
 			s.WriteString(fmt.Sprintf("%s ", e.Daddr))
@@ -73,7 +909,7 @@ func printRouteEntry(e *ip.RouteEntry) {
 	}
 
 	if e.Saddr != nil {
-		if e.SrcLen != 32 {
+		if e.SrcLen != len(e.Saddr)*8 {
 			s.WriteString(fmt.Sprintf("from %s/%d ", e.Saddr, e.SrcLen))
 		} else {
 			s.WriteString(fmt.Sprintf("from %s ", e.Saddr))
@@ -82,36 +918,51 @@ func printRouteEntry(e *ip.RouteEntry) {
 		s.WriteString(fmt.Sprintf("from 0/%d ", e.SrcLen))
 	}
 
+	if e.NexthopID != 0 {
+		s.WriteString(fmt.Sprintf("nhid %d ", e.NexthopID))
+	}
+
+	if len(e.NewDst) != 0 {
+		s.WriteString(fmt.Sprintf("as to %s ", formatMPLSLabels(e.NewDst)))
+	}
+
+	if e.Encap != nil {
+		s.WriteString(formatEncap(e.Encap))
+	}
+
 	if e.Tos != 0 {
-		s.WriteString(fmt.Sprintf("tos %d ", e.Tos))
+		s.WriteString(fmt.Sprintf("tos %s ", ip.DSField(e.Tos)))
 	}
 
-	if e.Gateway != nil {
+	if e.Gateway != nil && f.Gateway == nil {
 		s.WriteString(fmt.Sprintf("via %s ", e.Gateway))
 	}
 
-	if e.OutIfindex != 0 {
-		ifi, err := net.InterfaceByIndex(e.OutIfindex)
-		if err == nil {
-			s.WriteString(fmt.Sprintf("dev %s ", ifi.Name))
+	if e.Via != nil && f.Gateway == nil {
+		s.WriteString(fmt.Sprintf("via %s %s ", familyName(e.Via.Family), e.Via.Addr))
+	}
+
+	if e.OutIfindex != 0 && f.OutIfindex == 0 {
+		if name, ok := lookupIfname(e.OutIfindex); ok {
+			s.WriteString(fmt.Sprintf("dev %s ", name))
 		}
 	}
 
-	if int(table) != unix.RT_TABLE_MAIN {
+	if int(table) != unix.RT_TABLE_MAIN && f.AllTables {
 		s.WriteString(fmt.Sprintf("table %s ", table))
 	}
 
 	if int(e.Flags)&unix.RTM_F_CLONED == 0 {
-		if int(e.Protocol) != unix.RTPROT_BOOT {
+		if int(e.Protocol) != unix.RTPROT_BOOT && f.Protocol == 0 {
 			s.WriteString(fmt.Sprintf("proto %s ", e.Protocol))
 		}
 
-		if int(e.Scope) != unix.RT_SCOPE_UNIVERSE {
+		if int(e.Scope) != unix.RT_SCOPE_UNIVERSE && f.Scope == nil {
 			s.WriteString(fmt.Sprintf("scope %s ", e.Scope))
 		}
 	}
 
-	if e.PrefSrc != nil {
+	if e.PrefSrc != nil && f.PrefSrc == nil {
 		s.WriteString(fmt.Sprintf("src %s ", e.PrefSrc))
 	}
 
@@ -119,11 +970,73 @@ func printRouteEntry(e *ip.RouteEntry) {
 		s.WriteString(fmt.Sprintf("metric %d ", e.Priority))
 	}
 
-	s.WriteString(e.Flags.String())
+	if flags := e.Flags.String(); flags != "" {
+		s.WriteString(flags + " ")
+	}
+
+	if e.Mark != 0 {
+		s.WriteString(fmt.Sprintf("mark 0x%x ", e.Mark))
+	}
+
+	if e.UID != -1 {
+		s.WriteString(fmt.Sprintf("uid %d ", e.UID))
+	}
+
+	if e.Realms != 0 {
+		s.WriteString(formatRouteRealms(e.Realms))
+	}
+
+	if int(e.Flags)&unix.RTM_F_CLONED != 0 && e.Family == syscall.AF_INET {
+		s.WriteString("\n    cache ")
+		s.WriteString(formatRouteCacheFlags(e.Flags))
+		if e.CacheInfo != nil {
+			s.WriteString(formatRouteCacheInfo(e.CacheInfo))
+		}
+	} else if e.Family == syscall.AF_INET6 && e.CacheInfo != nil {
+		s.WriteString(formatRouteCacheInfo(e.CacheInfo))
+	}
+
+	if e.Metrics != nil {
+		s.WriteString(formatRouteMetrics(e.Metrics))
+	}
+
+	if e.InIfindex != 0 && f.InIfindex == 0 {
+		s.WriteString(fmt.Sprintf("iif %s ", ifname(e.InIfindex)))
+	}
 
 	if e.Pref != -1 {
 		s.WriteString(fmt.Sprintf("pref %s ", e.Pref))
 	}
 
+	if e.TTLProp != ip.MPLS_TTL_PROP_DEFAULT {
+		s.WriteString(fmt.Sprintf("ttl-propagate %s ", e.TTLProp))
+	}
+
+	for _, nh := range e.MultiPath {
+		s.WriteString("\n\tnexthop ")
+		if nh.Encap != nil {
+			s.WriteString(formatEncap(nh.Encap))
+		}
+		if len(nh.NewDst) != 0 {
+			s.WriteString(fmt.Sprintf("as to %s ", formatMPLSLabels(nh.NewDst)))
+		}
+		if nh.Gateway != nil {
+			s.WriteString(fmt.Sprintf("via %s ", nh.Gateway))
+		}
+		if nh.Via != nil {
+			s.WriteString(fmt.Sprintf("via %s %s ", familyName(nh.Via.Family), nh.Via.Addr))
+		}
+		if nh.Realms != 0 {
+			s.WriteString(formatRouteRealms(nh.Realms))
+		}
+		if nh.Ifindex != 0 {
+			s.WriteString(fmt.Sprintf("dev %s ", ifname(nh.Ifindex)))
+		}
+		s.WriteString(fmt.Sprintf("weight %d ", nh.Weight))
+		if flags := nh.Flags.String(); flags != "" {
+			s.WriteString(flags + " ")
+		}
+	}
+
 	fmt.Println(s.String())
 }
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	rootCmd.AddCommand(ruleCmd())
+}
+
+func ruleCmd() *cobra.Command {
+	ruleCmd := &cobra.Command{
+		Use:     "rule",
+		Aliases: []string{"ru", "rul"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listRules(args) })
+		},
+	}
+	ruleCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.listRules(args) })
+		},
+	})
+	ruleCmd.AddCommand(&cobra.Command{
+		Use:     "add",
+		Aliases: []string{"a", "ad"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.modifyRule(args, (*ip.Client).AddRule) })
+		},
+	})
+	ruleCmd.AddCommand(&cobra.Command{
+		Use:     "del",
+		Aliases: []string{"d", "de", "delete"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("\"ip rule del\" requires arguments.")
+				return
+			}
+			cli.runCmd(func() { cli.modifyRule(args, (*ip.Client).DelRule) })
+		},
+	})
+	ruleCmd.AddCommand(&cobra.Command{
+		Use:     "flush",
+		Aliases: []string{"f", "fl", "flu", "flus"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.flushRules(args) })
+		},
+	})
+	return ruleCmd
+}
+
+// ruleFamily is the family of the rules to list or flush, which is IPv4
+// unless it's given by the options like iproute2.
+func ruleFamily() int {
+	if preferredFamily == syscall.AF_UNSPEC {
+		return syscall.AF_INET
+	}
+	return preferredFamily
+}
+
+func (c *client) listRules(args []string) {
+	filter, err := parseRuleArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListRulesWithFilter(ruleFamily(), filter)
+	if err != nil {
+		fmt.Println("failed to list rules, err:", err)
+		return
+	}
+
+	for _, e := range entries {
+		printRuleEntry(e)
+	}
+}
+
+func (c *client) modifyRule(args []string, fn func(*ip.Client, *ip.RuleEntry) error) {
+	e, err := parseRuleArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := fn(ipcli, e); err != nil {
+		fmt.Println("failed to modify rule, err:", err)
+	}
+}
+
+func (c *client) flushRules(args []string) {
+	filter, err := parseRuleArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	n, err := ipcli.FlushRulesWithFilter(ruleFamily(), filter)
+	if err != nil {
+		fmt.Println("failed to flush rules, err:", err)
+		return
+	}
+	if showStats > 0 {
+		fmt.Printf("*** Flush is complete, %d rules deleted ***\n", n)
+	}
+}
+
+// parseRuleArgs parses the rule from the arguments, like
+// `from 10.0.0.0/8 fwmark 0x1/0xff lookup 100 prio 100`.
+func parseRuleArgs(args []string) (*ip.RuleEntry, error) {
+	var e ip.RuleEntry
+	e.Family = preferredFamily
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "not":
+			e.Flags |= ip.FIB_RULE_INVERT
+		case "from":
+			e.Src, e.SrcLen, err = p.prefix(key)
+		case "to":
+			e.Dst, e.DstLen, err = p.prefix(key)
+		case "preference", "order", "priority", "prio", "pref":
+			var prio uint64
+			prio, err = p.uint(key, 32)
+			e.Priority = int(prio)
+		case "tos", "dsfield":
+			e.Tos, err = p.dsfield(key)
+		case "fwmark":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Mark, e.Mask, err = parseMarkMask(val)
+			}
+		case "realms", "realm":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Realms, err = parseRealms(val)
+			}
+		case "table", "lookup":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Table, err = ip.ParseRouteTable(val)
+			}
+		case "protocol", "proto":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Protocol, err = ip.ParseRouteProtocol(val)
+			}
+		case "suppress_prefixlength", "sup_pl":
+			var val string
+			if val, err = p.value(key); err == nil {
+				var pl int
+				if pl, err = strconv.Atoi(val); err != nil || pl < -1 {
+					err = fmt.Errorf("invalid suppress_prefixlength \"%s\"", val)
+				}
+				e.SuppressPrefixLen = &pl
+			}
+		case "suppress_ifgroup", "sup_group":
+			var val string
+			if val, err = p.value(key); err == nil {
+				var group ip.LinkGroup
+				group, err = ip.ParseLinkGroup(val)
+				e.SuppressIfGroup = &group
+			}
+		case "dev", "iif":
+			e.IifName, err = p.value(key)
+		case "oif":
+			e.OifName, err = p.value(key)
+		case "l3mdev":
+			e.L3mdev = true
+		case "uidrange":
+			var val string
+			if val, err = p.value(key); err == nil {
+				var start, end uint64
+				start, end, err = parseRange(key, val, 32)
+				e.UIDRange = &ip.RuleUIDRange{Start: uint32(start), End: uint32(end)}
+			}
+		case "ipproto":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.IPProto, err = parseIPProto(val)
+			}
+		case "sport", "dport":
+			var val string
+			if val, err = p.value(key); err != nil {
+				break
+			}
+			var start, end uint64
+			start, end, err = parseRange(key, val, 16)
+			r := &ip.RulePortRange{Start: uint16(start), End: uint16(end)}
+			if key == "sport" {
+				e.SportRange = r
+			} else {
+				e.DportRange = r
+			}
+		case "tun_id":
+			e.TunID, err = p.uint(key, 64)
+		case "nat", "map-to":
+			e.NAT, err = p.addr(key)
+			e.Action, _ = ip.ParseRuleAction("nat")
+		case "goto":
+			var prio uint64
+			prio, err = p.uint(key, 32)
+			e.Goto = int(prio)
+			e.Action = ip.FR_ACT_GOTO
+		case "type":
+			var val string
+			if val, err = p.value(key); err == nil {
+				e.Action, err = ip.ParseRuleAction(val)
+			}
+		default:
+			if e.Action, err = ip.ParseRuleAction(key); err != nil {
+				err = fmt.Errorf("unknown rule argument \"%s\"", key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+// parseMarkMask parses the mark with an optional mask, like `0x1/0xff`.
+func parseMarkMask(s string) (uint32, uint32, error) {
+	mark, mask := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		mark, mask = s[:i], s[i+1:]
+	}
+	m, err := strconv.ParseUint(mark, 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid fwmark \"%s\"", s)
+	}
+	if mask == "" {
+		return uint32(m), 0, nil
+	}
+	k, err := strconv.ParseUint(mask, 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid fwmask \"%s\"", s)
+	}
+	return uint32(m), uint32(k), nil
+}
+
+// parseRange parses a range like `1000-2000`, or a single number.
+func parseRange(key, s string, bitSize int) (uint64, uint64, error) {
+	start, end := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		start, end = s[:i], s[i+1:]
+	}
+	a, err := strconv.ParseUint(start, 0, bitSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid %s \"%s\"", key, s)
+	}
+	b, err := strconv.ParseUint(end, 0, bitSize)
+	if err != nil || b < a {
+		return 0, 0, fmt.Errorf("invalid %s \"%s\"", key, s)
+	}
+	return a, b, nil
+}
+
+func printRuleEntry(e *ip.RuleEntry) {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("%d:\t", e.Priority))
+
+	if e.Flags&ip.FIB_RULE_INVERT != 0 {
+		s.WriteString("not ")
+	}
+
+	if e.Src != nil {
+		if e.SrcLen != len(e.Src)*8 {
+			s.WriteString(fmt.Sprintf("from %s/%d ", e.Src, e.SrcLen))
+		} else {
+			s.WriteString(fmt.Sprintf("from %s ", e.Src))
+		}
+	} else if e.SrcLen != 0 {
+		s.WriteString(fmt.Sprintf("from 0/%d ", e.SrcLen))
+	} else {
+		s.WriteString("from all ")
+	}
+
+	if e.Dst != nil {
+		if e.DstLen != len(e.Dst)*8 {
+			s.WriteString(fmt.Sprintf("to %s/%d ", e.Dst, e.DstLen))
+		} else {
+			s.WriteString(fmt.Sprintf("to %s ", e.Dst))
+		}
+	} else if e.DstLen != 0 {
+		s.WriteString(fmt.Sprintf("to 0/%d ", e.DstLen))
+	}
+
+	if e.Tos != 0 {
+		s.WriteString(fmt.Sprintf("tos %s ", ip.DSField(e.Tos)))
+	}
+
+	if e.Mark != 0 || e.Mask != 0 {
+		if e.Mask != 0xffffffff {
+			s.WriteString(fmt.Sprintf("fwmark 0x%x/0x%x ", e.Mark, e.Mask))
+		} else {
+			s.WriteString(fmt.Sprintf("fwmark 0x%x ", e.Mark))
+		}
+	}
+
+	if e.IifName != "" {
+		s.WriteString(fmt.Sprintf("iif %s ", e.IifName))
+		if e.Flags&ip.FIB_RULE_IIF_DETACHED != 0 {
+			s.WriteString("[detached] ")
+		}
+	}
+
+	if e.OifName != "" {
+		s.WriteString(fmt.Sprintf("oif %s ", e.OifName))
+		if e.Flags&ip.FIB_RULE_OIF_DETACHED != 0 {
+			s.WriteString("[detached] ")
+		}
+	}
+
+	if e.L3mdev {
+		s.WriteString("lookup [l3mdev-table] ")
+	}
+
+	if e.UIDRange != nil {
+		s.WriteString(fmt.Sprintf("uidrange %d-%d ", e.UIDRange.Start, e.UIDRange.End))
+	}
+
+	if e.IPProto != 0 {
+		s.WriteString(fmt.Sprintf("ipproto %s ", ipProtoName(e.IPProto)))
+	}
+
+	ports := []struct {
+		name string
+		r    *ip.RulePortRange
+	}{
+		{"sport", e.SportRange},
+		{"dport", e.DportRange},
+	}
+	for _, port := range ports {
+		if port.r == nil {
+			continue
+		}
+		if port.r.Start == port.r.End {
+			s.WriteString(fmt.Sprintf("%s %d ", port.name, port.r.Start))
+		} else {
+			s.WriteString(fmt.Sprintf("%s %d-%d ", port.name, port.r.Start, port.r.End))
+		}
+	}
+
+	if e.TunID != 0 {
+		s.WriteString(fmt.Sprintf("tun_id %d ", e.TunID))
+	}
+
+	if e.Table != 0 {
+		s.WriteString(fmt.Sprintf("lookup %s ", e.Table))
+		if e.SuppressPrefixLen != nil {
+			s.WriteString(fmt.Sprintf("suppress_prefixlength %d ", *e.SuppressPrefixLen))
+		}
+		if e.SuppressIfGroup != nil {
+			s.WriteString(fmt.Sprintf("suppress_ifgroup %s ", e.SuppressIfGroup))
+		}
+	}
+
+	if e.Realms != 0 {
+		s.WriteString(fmt.Sprintf("realms %s ", formatRealms(e.Realms)))
+	}
+
+	switch e.Action {
+	case ip.FR_ACT_TO_TBL:
+	case ip.FR_ACT_GOTO:
+		if e.Goto != 0 {
+			s.WriteString(fmt.Sprintf("goto %d ", e.Goto))
+		} else {
+			s.WriteString("goto none ")
+		}
+		if e.Flags&ip.FIB_RULE_UNRESOLVED != 0 {
+			s.WriteString("[unresolved] ")
+		}
+	default:
+		if e.NAT != nil {
+			s.WriteString(fmt.Sprintf("map-to %s ", e.NAT))
+		} else {
+			s.WriteString(e.Action.String() + " ")
+		}
+	}
+
+	if e.Protocol != 0 && e.Protocol != unix.RTPROT_KERNEL {
+		s.WriteString(fmt.Sprintf("proto %s ", e.Protocol))
+	}
+
+	fmt.Println(s.String())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(tokenCmd())
+}
+
+func tokenCmd() *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:     "token",
+		Aliases: []string{"to", "tok", "toke"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.listTokens)
+		},
+	}
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"l", "li", "lis", "lst", "s", "sh", "sho", "show"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(cli.listTokens)
+		},
+	})
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:     "get",
+		Aliases: []string{"g", "ge"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.getToken(args) })
+		},
+	})
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:     "set",
+		Aliases: []string{"se", "add"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.setToken(args) })
+		},
+	})
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:     "del",
+		Aliases: []string{"d", "de", "delete"},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli.runCmd(func() { cli.delToken(args) })
+		},
+	})
+	return tokenCmd
+}
+
+func (c *client) listTokens() {
+	ipcli := ip.NewWithConn(c.conn)
+	entries, err := ipcli.ListTokens()
+	if err != nil {
+		fmt.Println("failed to list tokens, err:", err)
+		return
+	}
+
+	for _, e := range entries {
+		printTokenEntry(e)
+	}
+}
+
+func (c *client) getToken(args []string) {
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		key := p.next()
+		if key == "dev" {
+			val, err := p.value(key)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			key = val
+		}
+		var err error
+		if ifindex, err = parseIfindex(key); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if ifindex == 0 {
+		c.listTokens()
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	e, err := ipcli.GetToken(ifindex)
+	if err != nil {
+		fmt.Println("failed to get token, err:", err)
+		return
+	}
+	printTokenEntry(e)
+}
+
+func (c *client) setToken(args []string) {
+	var token net.IP
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		switch key := p.next(); key {
+		case "dev":
+			val, err := p.value(key)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if ifindex, err = parseIfindex(val); err != nil {
+				fmt.Println(err)
+				return
+			}
+		default:
+			if token != nil {
+				fmt.Printf("unknown argument \"%s\"\n", key)
+				return
+			}
+			addr, bits, err := parsePrefix(key)
+			if err != nil || len(addr) != net.IPv6len || bits != 128 {
+				fmt.Printf("invalid token \"%s\"\n", key)
+				return
+			}
+			token = addr
+		}
+	}
+	if token == nil || ifindex == 0 {
+		fmt.Println("token and device are required")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := ipcli.SetToken(ifindex, token); err != nil {
+		fmt.Println("failed to set token, err:", err)
+	}
+}
+
+// delToken deletes the token of the device, which is set to all zeros
+// like iproute2.
+func (c *client) delToken(args []string) {
+	var ifindex int
+	p := newArgParser(args)
+	for p.more() {
+		var err error
+		switch key := p.next(); key {
+		case "dev":
+			ifindex, err = p.ifindex(key)
+		default:
+			err = fmt.Errorf("unknown argument \"%s\"", key)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if ifindex == 0 {
+		fmt.Println("Not enough information: \"dev\" argument is required.")
+		return
+	}
+
+	ipcli := ip.NewWithConn(c.conn)
+	if err := ipcli.SetToken(ifindex, net.IPv6zero); err != nil {
+		fmt.Println("failed to delete token, err:", err)
+	}
+}
+
+func printTokenEntry(e *ip.TokenEntry) {
+	fmt.Printf("token %s dev %s\n", e.Token, ifname(e.Ifindex))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2/ip"
+	"github.com/spf13/cobra"
+)
+
+var config struct {
+	ignoreHistory bool
+	noUpdate      bool
+	reset         bool
+	zeros         bool
+	noOutput      bool
+}
+
+var rootCmd = cobra.Command{
+	Use:   "rtacct [realms]",
+	Short: "show the traffic accounting of the route realms",
+	Long: `Show the traffic accounting of the route realms from /proc/net/rt_acct.
+
+The increments since the last time are shown, and the rates are computed
+from them, while the counters are saved in the history file, which is
+$RTACCT_HISTORY or .gortacct.u<UID> in the temporary directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rtacct(args); err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+func init() {
+	flags := rootCmd.Flags()
+	flags.BoolVarP(&config.ignoreHistory, "ignore", "a", false, "show the absolute values instead of the increments")
+	flags.BoolVarP(&config.noUpdate, "noupdate", "s", false, "do not update the history")
+	flags.BoolVarP(&config.reset, "reset", "r", false, "reset the history")
+	flags.BoolVarP(&config.zeros, "zeros", "z", false, "show the realms of zero counters too")
+	flags.BoolVarP(&config.noOutput, "nooutput", "n", false, "update the history only")
+}
+
+// historyFile is the file saving the counters of the last time.
+func historyFile() string {
+	if file := os.Getenv("RTACCT_HISTORY"); file != "" {
+		return file
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf(".gortacct.u%d", os.Getuid()))
+}
+
+func rtacct(args []string) error {
+	realms, err := parseRealmList(args)
+	if err != nil {
+		return err
+	}
+
+	accts, err := ip.ListRealmAccts()
+	if err != nil {
+		return fmt.Errorf("failed to read realm accounting, err: %w", err)
+	}
+
+	file := historyFile()
+	var hist map[int]*ip.RealmAcct
+	var elapsed time.Duration
+	if !config.ignoreHistory && !config.reset {
+		hist, elapsed, err = readHistory(file)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read history, err: %w", err)
+		}
+	}
+
+	if !config.noOutput {
+		printAccts(accts, hist, elapsed, realms)
+	}
+
+	if !config.noUpdate {
+		if err := writeHistory(file, accts); err != nil {
+			return fmt.Errorf("failed to write history, err: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseRealmList parses the realms to show, which are all the realms if
+// none is given.
+func parseRealmList(args []string) (map[int]bool, error) {
+	realms := make(map[int]bool)
+	for _, arg := range args {
+		realm, ok := ip.RealmNames.ID(arg)
+		if !ok || realm > 0xff {
+			return nil, fmt.Errorf("invalid realm \"%s\"", arg)
+		}
+		realms[realm] = true
+	}
+	return realms, nil
+}
+
+// readHistory reads the counters of the last time by the realms, and the
+// time elapsed since then. Like rtacct, the history must be owned by the
+// user and not be a symlink, as it may be in the temporary directory.
+func readHistory(file string) (map[int]*ip.RealmAcct, time.Duration, error) {
+	fd, err := os.OpenFile(file, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer fd.Close()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return nil, 0, fmt.Errorf("history %s is not owned by the user", file)
+	}
+
+	accts := make(map[int]*ip.RealmAcct)
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var a ip.RealmAcct
+		_, err := fmt.Sscanf(scanner.Text(), "%d %d %d %d %d",
+			&a.Realm, &a.BytesTo, &a.PacketsTo, &a.BytesFrom, &a.PacketsFrom)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid history %s", file)
+		}
+		accts[a.Realm] = &a
+	}
+	return accts, time.Since(fi.ModTime()), scanner.Err()
+}
+
+func writeHistory(file string, accts []*ip.RealmAcct) error {
+	var s strings.Builder
+	for _, a := range accts {
+		s.WriteString(fmt.Sprintf("%d %d %d %d %d\n",
+			a.Realm, a.BytesTo, a.PacketsTo, a.BytesFrom, a.PacketsFrom))
+	}
+
+	// the temporary file is created exclusively with a random name, as the
+	// directory may be writable by the others.
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(s.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// formatCount formats the counter like rtacct, which is in K or M if it's
+// large.
+func formatCount(val uint64) string {
+	switch {
+	case val < 1024*1024*10:
+		return fmt.Sprintf("%d", val)
+	case val < 1024*1024*1024*10:
+		return fmt.Sprintf("%dK", val/1024)
+	default:
+		return fmt.Sprintf("%dM", val/(1024*1024))
+	}
+}
+
+// formatRate formats the rate like rtacct, which is in K or M if it's
+// large.
+func formatRate(rate float64) string {
+	switch {
+	case rate < 1024*10:
+		return fmt.Sprintf("%.0f", rate)
+	case rate < 1024*1024*10:
+		return fmt.Sprintf("%.0fK", rate/1024)
+	default:
+		return fmt.Sprintf("%.0fM", rate/(1024*1024))
+	}
+}
+
+func printAccts(accts []*ip.RealmAcct, hist map[int]*ip.RealmAcct, elapsed time.Duration, realms map[int]bool) {
+	fmt.Println("#kernel")
+	fmt.Printf("%-10s %-10s %-10s %-10s %-10s \n", "Realm", "BytesTo", "PktsTo", "BytesFrom", "PktsFrom")
+	fmt.Printf("%-10s %-10s %-10s %-10s %-10s \n", "", "BPSTo", "PPSTo", "BPSFrom", "PPSFrom")
+
+	for _, a := range accts {
+		if len(realms) != 0 && !realms[a.Realm] {
+			continue
+		}
+
+		var rates [4]float64
+		if h, ok := hist[a.Realm]; ok {
+			a = a.Sub(h)
+			if secs := elapsed.Seconds(); secs > 0 {
+				rates = [4]float64{
+					float64(a.BytesTo) / secs,
+					float64(a.PacketsTo) / secs,
+					float64(a.BytesFrom) / secs,
+					float64(a.PacketsFrom) / secs,
+				}
+			}
+		}
+		if a.IsZero() && !config.zeros {
+			continue
+		}
+
+		var s strings.Builder
+		s.WriteString(fmt.Sprintf("%-10s", ip.RealmNames.Format(a.Realm)))
+		for _, val := range []uint32{a.BytesTo, a.PacketsTo, a.BytesFrom, a.PacketsFrom} {
+			s.WriteString(fmt.Sprintf(" %10s", formatCount(uint64(val))))
+		}
+		s.WriteString(fmt.Sprintf("\n%-10s", ""))
+		for _, rate := range rates {
+			s.WriteString(fmt.Sprintf(" %-10s", formatRate(rate)))
+		}
+		fmt.Println(s.String())
+	}
+}
+
+func main() {
+	rootCmd.Execute()
+}
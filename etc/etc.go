@@ -0,0 +1,216 @@
+// Package etc reads the configuration files of iproute2, which map the
+// ids to their names, like the route tables in */etc/iproute2/rt_tables*.
+//
+// A file is looked up in Dirs in order, and the first one found is read.
+// The files with a .d directory, like *rt_tables.d*, are followed by the
+// *.conf files in the directories, which override the names of the file.
+package etc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dirs are the directories of the configuration files in lookup order,
+// the same as iproute2.
+var Dirs = []string{
+	"/etc/iproute2",
+	"/usr/lib/iproute2",
+	"/usr/share/iproute2",
+}
+
+// The configuration files of iproute2.
+const (
+	RouteTableConf    = "rt_tables"
+	RouteProtocolConf = "rt_protos"
+	RouteScopeConf    = "rt_scopes"
+	RouteRealmConf    = "rt_realms"
+	DSFieldConf       = "rt_dsfield"
+	GroupConf         = "group"
+	NetlinkProtoConf  = "nl_protos"
+	EmatchMapConf     = "ematch_map"
+	BPFPinningConf    = "bpf_pinning"
+	AddrProtocolConf  = "rt_addrprotos"
+)
+
+// conf describes how a configuration file is read.
+type conf struct {
+	// maxID is the max id of the file, the lines with greater ids are
+	// ignored. It's 0 for no limit.
+	maxID int
+	// hasDir is whether the file has a .d directory.
+	hasDir bool
+}
+
+var confs = map[string]conf{
+	RouteTableConf:    {hasDir: true},
+	RouteProtocolConf: {maxID: 255, hasDir: true},
+	RouteScopeConf:    {maxID: 255},
+	RouteRealmConf:    {maxID: 255},
+	DSFieldConf:       {maxID: 255},
+	GroupConf:         {},
+	NetlinkProtoConf:  {maxID: 255},
+	EmatchMapConf:     {},
+	BPFPinningConf:    {},
+	AddrProtocolConf:  {maxID: 255, hasDir: true},
+}
+
+func ReadRouteTables() (map[int]string, error)   { return Read(RouteTableConf) }
+func ReadRouteProtos() (map[int]string, error)   { return Read(RouteProtocolConf) }
+func ReadRouteScopes() (map[int]string, error)   { return Read(RouteScopeConf) }
+func ReadRealms() (map[int]string, error)        { return Read(RouteRealmConf) }
+func ReadDSField() (map[int]string, error)       { return Read(DSFieldConf) }
+func ReadGroup() (map[int]string, error)         { return Read(GroupConf) }
+func ReadNetlinkProtos() (map[int]string, error) { return Read(NetlinkProtoConf) }
+func ReadEmatchMap() (map[int]string, error)     { return Read(EmatchMapConf) }
+func ReadBPFPinning() (map[int]string, error)    { return Read(BPFPinningConf) }
+func ReadAddrProtos() (map[int]string, error)    { return Read(AddrProtocolConf) }
+
+// Read reads the configuration file named name, like `rt_tables`, from
+// Dirs, with the *.conf files in its .d directory if it has one.
+//
+// Like iproute2, a corrupted file is read until the corrupted line, and
+// the other files are still read. The names are returned with the first
+// error. The error satisfies errors.Is(err, os.ErrNotExist) if neither
+// the file nor its .d directory exists.
+func Read(name string) (map[int]string, error) {
+	c := confs[name]
+	m := make(map[int]string)
+
+	found := false
+	var firstErr, notExist error
+	for _, dir := range Dirs {
+		err := readFile(filepath.Join(dir, name), c.maxID, m)
+		if errors.Is(err, os.ErrNotExist) {
+			notExist = err
+			continue
+		}
+		firstErr = err
+		found = true
+		break
+	}
+
+	if c.hasDir {
+		files, err := confDirFiles(name + ".d")
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		for _, file := range files {
+			if err := readFile(file, c.maxID, m); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return m, notExist
+	}
+	return m, firstErr
+}
+
+// confDirFiles gets the *.conf files in the .d directory of Dirs. A file
+// in an earlier directory overrides the one of the same name in the later
+// ones, and the files are sorted by their names.
+func confDirFiles(dirName string) ([]string, error) {
+	files := make(map[string]string)
+	for i := len(Dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(filepath.Join(Dirs[i], dirName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".conf") {
+				continue
+			}
+			files[e.Name()] = filepath.Join(Dirs[i], dirName, e.Name())
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		paths = append(paths, files[name])
+	}
+	return paths, nil
+}
+
+// ReadFile reads the configuration file of the path, without limiting the
+// ids.
+func ReadFile(path string) (map[int]string, error) {
+	m := make(map[int]string)
+	err := readFile(path, 0, m)
+	return m, err
+}
+
+func readFile(path string, maxID int, m map[int]string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	if err := parse(fd, maxID, m); err != nil {
+		return fmt.Errorf("etc: database %s is corrupted at %w", path, err)
+	}
+	return nil
+}
+
+// errCorrupted is the line which can't be parsed.
+type errCorrupted string
+
+func (e errCorrupted) Error() string { return strconv.Quote(string(e)) }
+
+// parse parses the lines like `ID NAME [# COMMENT]` to m, where the id is
+// a decimal or hexadecimal number, and they are separated by spaces or
+// tabs. The empty lines and the comments are skipped, and the parsing
+// stops at the first corrupted line like iproute2, while the words after
+// the name are ignored as iproute2 does.
+func parse(r io.Reader, maxID int, m map[int]string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return errCorrupted(line)
+		}
+		id, err := parseID(fields[0])
+		if err != nil {
+			return errCorrupted(line)
+		}
+		if id < 0 || maxID != 0 && id > maxID {
+			continue
+		}
+		m[id] = fields[1]
+	}
+	return scanner.Err()
+}
+
+// parseID parses the id like `0x10` or `16`.
+func parseID(s string) (int, error) {
+	if strings.HasPrefix(s, "0x") {
+		id, err := strconv.ParseUint(s[2:], 16, 32)
+		return int(id), err
+	}
+	id, err := strconv.ParseInt(s, 10, 32)
+	return int(id), err
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/Asphaltt/go-iproute2"
 	"github.com/mdlayher/netlink"
@@ -166,6 +167,29 @@ func (s AddrScope) String() string {
 	return fmt.Sprintf("%d", s)
 }
 
+const (
+	IFA_PROTO = 0xb
+
+	INFINITY_LIFE_TIME = 0xFFFFFFFF
+)
+
+// AddrProtocol is the protocol which installed the address.
+type AddrProtocol uint8
+
+// protocols for IFA_PROTO
+const (
+	AddrProtoUnspec AddrProtocol = iota
+	AddrProtoKernelLo
+	AddrProtoKernelRA
+	AddrProtoKernelLL
+)
+
+// String returns the string description of the AddrProtocol.
+// The user defined names are from */etc/iproute2/rt_addrprotos*.
+func (p AddrProtocol) String() string {
+	return AddrProtocolNames.Format(int(p))
+}
+
 type AddrEntry struct {
 	Family        int
 	PrefixLen     int
@@ -181,11 +205,51 @@ type AddrEntry struct {
 	MulticastAddr net.IP
 	AddrFlags     AddrFlag
 	AddrInfo      *iproute2.IfaCacheinfo
+	Protocol      AddrProtocol
+	RoutePriority int
+	NetNSID       int
+
+	// Created and Updated are converted from the cstamp and tstamp of
+	// AddrInfo.
+	Created time.Time
+	Updated time.Time
+	// ValidUntil and PreferredUntil are computed from the lifetimes of
+	// AddrInfo when the message is received, and are zero for the
+	// forever lifetimes.
+	ValidUntil     time.Time
+	PreferredUntil time.Time
 }
 
 func (e *AddrEntry) init() {
 	e.Scope = -1
 	e.AddrFlags = -1
+	e.RoutePriority = -1
+	e.NetNSID = -1
+}
+
+// setCacheinfo sets the timestamps and the expiry times of the address
+// from its cache info.
+func (e *AddrEntry) setCacheinfo(now time.Time) {
+	i := e.AddrInfo
+	boot := bootTime(now)
+	e.Created = boot.Add(time.Duration(i.Cstamp) * 10 * time.Millisecond)
+	e.Updated = boot.Add(time.Duration(i.Tstamp) * 10 * time.Millisecond)
+	if i.Valid != INFINITY_LIFE_TIME {
+		e.ValidUntil = now.Add(time.Duration(i.Valid) * time.Second)
+	}
+	if i.Prefered != INFINITY_LIFE_TIME {
+		e.PreferredUntil = now.Add(time.Duration(i.Prefered) * time.Second)
+	}
+}
+
+// bootTime gets the time when the system booted, because the kernel
+// stamps are relative to it.
+func bootTime(now time.Time) time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Time{}
+	}
+	return now.Add(-time.Duration(ts.Nano())).Round(0)
 }
 
 // ListAddresses gets all addresses information of links from kernel
@@ -208,6 +272,38 @@ func (c *Client) ListAddresses() (map[int][]*AddrEntry, error) {
 	}
 	msg.Data = append(msg.Data, data...)
 
+	return c.listAddresses(msg)
+}
+
+// ListAddressesNetNS gets all addresses information of links in the peer
+// network namespace, which is identified by the nsid in current network
+// namespace.
+func (c *Client) ListAddressesNetNS(nsid int) (map[int][]*AddrEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETADDR
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ifamsg iproute2.IfAddrMsg
+	ae := netlink.NewAttributeEncoder()
+	ae.Int32(unix.IFA_TARGET_NETNSID, int32(nsid))
+	msg.Data, _ = ifamsg.MarshalBinary()
+	data, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = append(msg.Data, data...)
+
+	// kernel only accepts IFA_TARGET_NETNSID with strict checking.
+	restore, err := c.enableStrictCheck()
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	return c.listAddresses(msg)
+}
+
+func (c *Client) listAddresses(msg netlink.Message) (map[int][]*AddrEntry, error) {
 	msgs, err := c.conn.Execute(msg)
 	if err != nil {
 		return nil, err
@@ -269,8 +365,17 @@ func parseAddrMsg(msg *netlink.Message) (*AddrEntry, bool, error) {
 			e.MulticastAddr = net.IP(ad.Bytes())
 		case unix.IFA_FLAGS:
 			e.AddrFlags = AddrFlag(ad.Uint32())
+		case unix.IFA_RT_PRIORITY:
+			e.RoutePriority = int(ad.Uint32())
+		case unix.IFA_TARGET_NETNSID:
+			e.NetNSID = int(ad.Int32())
+		case IFA_PROTO:
+			e.Protocol = AddrProtocol(ad.Uint8())
 		}
 	}
+	if e.AddrInfo != nil {
+		e.setCacheinfo(time.Now())
+	}
 	err = ad.Err()
 	return &e, err == nil, err
 }
@@ -0,0 +1,142 @@
+package ip
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// attribute types for address label message
+const (
+	IFAL_ADDRESS = 0x1
+	IFAL_LABEL   = 0x2
+)
+
+// An AddrLabelEntry is an IPv6 source address selection label,
+// see RFC 6724 for the policy table.
+type AddrLabelEntry struct {
+	Prefix    net.IP
+	PrefixLen int
+	Label     uint32
+	Ifindex   int
+	Seq       uint32
+}
+
+// ListAddrLabels dumps the IPv6 address label table from kernel.
+func (c *Client) ListAddrLabels() ([]*AddrLabelEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETADDRLABEL
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ifalmsg iproute2.IfAddrLblMsg
+	ifalmsg.Family = syscall.AF_INET6
+	msg.Data, _ = ifalmsg.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*AddrLabelEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWADDRLABEL {
+			continue
+		}
+
+		e, ok, err := parseAddrLabelMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// AddAddrLabel adds an IPv6 address label to kernel.
+func (c *Client) AddAddrLabel(e *AddrLabelEntry) error {
+	return c.modifyAddrLabel(unix.RTM_NEWADDRLABEL, netlink.Create|netlink.Excl, e)
+}
+
+// DelAddrLabel deletes an IPv6 address label from kernel.
+func (c *Client) DelAddrLabel(e *AddrLabelEntry) error {
+	return c.modifyAddrLabel(unix.RTM_DELADDRLABEL, 0, e)
+}
+
+// FlushAddrLabels deletes all IPv6 address labels, including the default
+// ones installed by kernel.
+func (c *Client) FlushAddrLabels() error {
+	entries, err := c.ListAddrLabels()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := c.DelAddrLabel(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) modifyAddrLabel(typ netlink.HeaderType, flags netlink.HeaderFlags, e *AddrLabelEntry) error {
+	if len(e.Prefix) != net.IPv6len {
+		return errors.New("address label: prefix must be an IPv6 address")
+	}
+
+	var msg netlink.Message
+	msg.Header.Type = typ
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge | flags
+
+	var ifalmsg iproute2.IfAddrLblMsg
+	ifalmsg.Family = syscall.AF_INET6
+	ifalmsg.Prefixlen = uint8(e.PrefixLen)
+	ifalmsg.Index = uint32(e.Ifindex)
+	msg.Data, _ = ifalmsg.MarshalBinary()
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Bytes(IFAL_ADDRESS, e.Prefix)
+	ae.Uint32(IFAL_LABEL, e.Label)
+	data, err := ae.Encode()
+	if err != nil {
+		return err
+	}
+	msg.Data = append(msg.Data, data...)
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+// parseAddrLabelMsg parses an address label from a netlink message.
+func parseAddrLabelMsg(msg *netlink.Message) (*AddrLabelEntry, bool, error) {
+	var ifalmsg iproute2.IfAddrLblMsg
+	if err := ifalmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e AddrLabelEntry
+	e.PrefixLen = int(ifalmsg.Prefixlen)
+	e.Ifindex = int(ifalmsg.Index)
+	e.Seq = ifalmsg.Seq
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofIfAddrLblMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case IFAL_ADDRESS:
+			e.Prefix = net.IP(ad.Bytes())
+		case IFAL_LABEL:
+			e.Label = ad.Uint32()
+		}
+	}
+	err = ad.Err()
+	return &e, err == nil, err
+}
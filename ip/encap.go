@@ -0,0 +1,793 @@
+package ip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	LWTUNNEL_IP_ID    = 0x1
+	LWTUNNEL_IP_DST   = 0x2
+	LWTUNNEL_IP_SRC   = 0x3
+	LWTUNNEL_IP_TTL   = 0x4
+	LWTUNNEL_IP_TOS   = 0x5
+	LWTUNNEL_IP_FLAGS = 0x6
+	LWTUNNEL_IP_OPTS  = 0x8
+
+	LWTUNNEL_IP6_ID       = 0x1
+	LWTUNNEL_IP6_DST      = 0x2
+	LWTUNNEL_IP6_SRC      = 0x3
+	LWTUNNEL_IP6_HOPLIMIT = 0x4
+	LWTUNNEL_IP6_TC       = 0x5
+	LWTUNNEL_IP6_FLAGS    = 0x6
+	LWTUNNEL_IP6_OPTS     = 0x8
+
+	LWT_BPF_IN            = 0x1
+	LWT_BPF_OUT           = 0x2
+	LWT_BPF_XMIT          = 0x3
+	LWT_BPF_XMIT_HEADROOM = 0x4
+
+	LWT_BPF_PROG_FD   = 0x1
+	LWT_BPF_PROG_NAME = 0x2
+
+	SEG6_IPTUNNEL_SRH = 0x1
+
+	SEG6_LOCAL_ACTION   = 0x1
+	SEG6_LOCAL_SRH      = 0x2
+	SEG6_LOCAL_TABLE    = 0x3
+	SEG6_LOCAL_NH4      = 0x4
+	SEG6_LOCAL_NH6      = 0x5
+	SEG6_LOCAL_IIF      = 0x6
+	SEG6_LOCAL_OIF      = 0x7
+	SEG6_LOCAL_BPF      = 0x8
+	SEG6_LOCAL_VRFTABLE = 0x9
+	SEG6_LOCAL_COUNTERS = 0xa
+
+	SEG6_LOCAL_BPF_PROG      = 0x1
+	SEG6_LOCAL_BPF_PROG_NAME = 0x2
+
+	SEG6_LOCAL_CNT_PACKETS = 0x2
+	SEG6_LOCAL_CNT_BYTES   = 0x3
+	SEG6_LOCAL_CNT_ERRORS  = 0x4
+
+	IOAM6_IPTUNNEL_MODE   = 0x1
+	IOAM6_IPTUNNEL_DST    = 0x2
+	IOAM6_IPTUNNEL_TRACE  = 0x3
+	IOAM6_IPTUNNEL_FREQ_K = 0x4
+	IOAM6_IPTUNNEL_FREQ_N = 0x5
+)
+
+// the flags of the ip tunnel metadata, which are in network order in
+// LWTUNNEL_IP_FLAGS.
+const (
+	TUNNEL_CSUM = 0x1
+	TUNNEL_KEY  = 0x4
+	TUNNEL_SEQ  = 0x8
+)
+
+// the segment routing header.
+const (
+	ipv6SRHType      = 4
+	sizeofIPv6SRH    = 8
+	sr6FlagHMAC      = 1 << 3
+	sr6TLVHMAC       = 5
+	sizeofSR6TLVHMAC = 40
+)
+
+// RouteEncap is a lightweight tunnel encapsulation of a route or
+// nexthop from the RTA_ENCAP attribute.
+// It's one of *MPLSEncap, *IPEncap, *Seg6Encap, *Seg6LocalEncap,
+// *BPFEncap, *IOAM6Encap and *RawEncap.
+type RouteEncap interface {
+	// EncapType returns the LWTUNNEL_ENCAP_* type of the encapsulation.
+	EncapType() int
+	encode(ae *netlink.AttributeEncoder) error
+}
+
+// MPLSEncap pushes the MPLS labels, whose first one is the outermost.
+type MPLSEncap struct {
+	Labels []uint32
+	TTL    int
+}
+
+// EncapType returns LWTUNNEL_ENCAP_MPLS.
+func (e *MPLSEncap) EncapType() int { return unix.LWTUNNEL_ENCAP_MPLS }
+
+func (e *MPLSEncap) encode(ae *netlink.AttributeEncoder) error {
+	ae.Bytes(unix.MPLS_IPTUNNEL_DST, marshalMPLSLabels(e.Labels))
+	if e.TTL != 0 {
+		ae.Uint8(unix.MPLS_IPTUNNEL_TTL, uint8(e.TTL))
+	}
+	return nil
+}
+
+// IPEncapFlags is the TUNNEL_* flags of the ip tunnel metadata.
+type IPEncapFlags uint16
+
+// IPEncap is the metadata of the ip tunnel for the collect_md tunnel
+// devices. Family is AF_INET6 for the ip6 encapsulation, whose TTL and
+// Tos are the hoplimit and the traffic class.
+// Opts is the raw nested attributes of LWTUNNEL_IP_OPTS.
+type IPEncap struct {
+	Family int
+	ID     uint64
+	Dst    net.IP
+	Src    net.IP
+	TTL    int
+	Tos    int
+	Flags  IPEncapFlags
+	Opts   []byte
+}
+
+// EncapType returns LWTUNNEL_ENCAP_IP6 for AF_INET6, and
+// LWTUNNEL_ENCAP_IP for the others.
+func (e *IPEncap) EncapType() int {
+	if e.Family == syscall.AF_INET6 {
+		return unix.LWTUNNEL_ENCAP_IP6
+	}
+	return unix.LWTUNNEL_ENCAP_IP
+}
+
+func (e *IPEncap) encode(ae *netlink.AttributeEncoder) error {
+	family := syscall.AF_INET
+	if e.Family == syscall.AF_INET6 {
+		family = syscall.AF_INET6
+	}
+
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, e.ID)
+	ae.Bytes(LWTUNNEL_IP_ID, id)
+	for _, a := range []struct {
+		typ  uint16
+		addr net.IP
+	}{
+		{LWTUNNEL_IP_DST, e.Dst},
+		{LWTUNNEL_IP_SRC, e.Src},
+	} {
+		if a.addr == nil {
+			continue
+		}
+		addr, err := familyAddr(family, a.addr)
+		if err != nil {
+			return err
+		}
+		ae.Bytes(a.typ, addr)
+	}
+	if e.TTL != 0 {
+		ae.Uint8(LWTUNNEL_IP_TTL, uint8(e.TTL))
+	}
+	if e.Tos != 0 {
+		ae.Uint8(LWTUNNEL_IP_TOS, uint8(e.Tos))
+	}
+	if e.Flags != 0 {
+		flags := make([]byte, 2)
+		binary.BigEndian.PutUint16(flags, uint16(e.Flags))
+		ae.Bytes(LWTUNNEL_IP_FLAGS, flags)
+	}
+	if e.Opts != nil {
+		ae.Bytes(LWTUNNEL_IP_OPTS|unix.NLA_F_NESTED, e.Opts)
+	}
+	return nil
+}
+
+// Seg6Mode is the SEG6_IPTUN_MODE_* mode of the seg6 encapsulation.
+type Seg6Mode int
+
+// the seg6 encapsulation modes.
+const (
+	SEG6_IPTUN_MODE_INLINE      = 0x0
+	SEG6_IPTUN_MODE_ENCAP       = 0x1
+	SEG6_IPTUN_MODE_L2ENCAP     = 0x2
+	SEG6_IPTUN_MODE_ENCAP_RED   = 0x3
+	SEG6_IPTUN_MODE_L2ENCAP_RED = 0x4
+)
+
+var seg6ModeNames = []string{"inline", "encap", "l2encap", "encap.red", "l2encap.red"}
+
+// String returns the name of the mode like iproute2.
+func (m Seg6Mode) String() string {
+	if m >= 0 && int(m) < len(seg6ModeNames) {
+		return seg6ModeNames[m]
+	}
+	return fmt.Sprintf("%d", m)
+}
+
+// ParseSeg6Mode parses a seg6 mode from its name, like `encap`.
+func ParseSeg6Mode(s string) (Seg6Mode, error) {
+	for i, name := range seg6ModeNames {
+		if name == s {
+			return Seg6Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid seg6 mode \"%s\"", s)
+}
+
+// Seg6Encap inserts or encapsulates the packets with the segment
+// routing header. The segments are in the order of the path, and the
+// last one of the inline mode is the placeholder of the original
+// destination. HMAC is the key id of the HMAC TLV, which is added if
+// it's not zero.
+type Seg6Encap struct {
+	Mode     Seg6Mode
+	Segments []net.IP
+	HMAC     uint32
+}
+
+// EncapType returns LWTUNNEL_ENCAP_SEG6.
+func (e *Seg6Encap) EncapType() int { return unix.LWTUNNEL_ENCAP_SEG6 }
+
+func (e *Seg6Encap) encode(ae *netlink.AttributeEncoder) error {
+	srh, err := marshalSRH(e.Segments, e.HMAC)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, 4, 4+len(srh))
+	nlenc.PutInt32(data, int32(e.Mode))
+	ae.Bytes(SEG6_IPTUNNEL_SRH, append(data, srh...))
+	return nil
+}
+
+// marshalSRH marshals the segments to the struct ipv6_sr_hdr, whose
+// segment list is in the reverse order of the path.
+func marshalSRH(segments []net.IP, hmac uint32) ([]byte, error) {
+	if len(segments) == 0 || len(segments) > 128 {
+		return nil, errors.New("seg6: invalid number of segments")
+	}
+
+	size := sizeofIPv6SRH + len(segments)*net.IPv6len
+	if hmac != 0 {
+		size += sizeofSR6TLVHMAC
+	}
+	data := make([]byte, size)
+	data[1] = uint8((size - 8) / 8)
+	data[2] = ipv6SRHType
+	data[3] = uint8(len(segments) - 1)
+	data[4] = uint8(len(segments) - 1)
+	if hmac != 0 {
+		data[5] = sr6FlagHMAC
+	}
+
+	for i, seg := range segments {
+		addr := seg.To16()
+		if addr == nil || seg.To4() != nil {
+			return nil, fmt.Errorf("seg6: invalid segment %s", seg)
+		}
+		off := sizeofIPv6SRH + (len(segments)-1-i)*net.IPv6len
+		copy(data[off:], addr)
+	}
+
+	if hmac != 0 {
+		tlv := data[sizeofIPv6SRH+len(segments)*net.IPv6len:]
+		tlv[0] = sr6TLVHMAC
+		tlv[1] = sizeofSR6TLVHMAC - 2
+		binary.BigEndian.PutUint32(tlv[4:], hmac)
+	}
+	return data, nil
+}
+
+// unmarshalSRH unmarshals the segments and the key id of the HMAC TLV
+// from the struct ipv6_sr_hdr.
+func unmarshalSRH(data []byte) ([]net.IP, uint32, error) {
+	if len(data) < sizeofIPv6SRH {
+		return nil, 0, errors.New("seg6: not enough data to unmarshal")
+	}
+
+	n := int(data[4]) + 1
+	if len(data) < sizeofIPv6SRH+n*net.IPv6len {
+		return nil, 0, errors.New("seg6: invalid segment list")
+	}
+	segments := make([]net.IP, n)
+	for i := range segments {
+		off := sizeofIPv6SRH + (n-1-i)*net.IPv6len
+		segments[i] = net.IP(data[off : off+net.IPv6len])
+	}
+
+	var hmac uint32
+	tlv := data[sizeofIPv6SRH+n*net.IPv6len:]
+	if data[5]&sr6FlagHMAC != 0 && len(tlv) >= sizeofSR6TLVHMAC && tlv[0] == sr6TLVHMAC {
+		hmac = binary.BigEndian.Uint32(tlv[4:])
+	}
+	return segments, hmac, nil
+}
+
+// Seg6LocalAction is the SEG6_LOCAL_ACTION_* action of the seg6local
+// encapsulation.
+type Seg6LocalAction int
+
+// the seg6local actions.
+const (
+	SEG6_LOCAL_ACTION_END          = 0x1
+	SEG6_LOCAL_ACTION_END_X        = 0x2
+	SEG6_LOCAL_ACTION_END_T        = 0x3
+	SEG6_LOCAL_ACTION_END_DX2      = 0x4
+	SEG6_LOCAL_ACTION_END_DX6      = 0x5
+	SEG6_LOCAL_ACTION_END_DX4      = 0x6
+	SEG6_LOCAL_ACTION_END_DT6      = 0x7
+	SEG6_LOCAL_ACTION_END_DT4      = 0x8
+	SEG6_LOCAL_ACTION_END_B6       = 0x9
+	SEG6_LOCAL_ACTION_END_B6_ENCAP = 0xa
+	SEG6_LOCAL_ACTION_END_BM       = 0xb
+	SEG6_LOCAL_ACTION_END_S        = 0xc
+	SEG6_LOCAL_ACTION_END_AS       = 0xd
+	SEG6_LOCAL_ACTION_END_AM       = 0xe
+	SEG6_LOCAL_ACTION_END_BPF      = 0xf
+	SEG6_LOCAL_ACTION_END_DT46     = 0x10
+)
+
+var seg6LocalActionNames = map[Seg6LocalAction]string{
+	SEG6_LOCAL_ACTION_END:          "End",
+	SEG6_LOCAL_ACTION_END_X:        "End.X",
+	SEG6_LOCAL_ACTION_END_T:        "End.T",
+	SEG6_LOCAL_ACTION_END_DX2:      "End.DX2",
+	SEG6_LOCAL_ACTION_END_DX6:      "End.DX6",
+	SEG6_LOCAL_ACTION_END_DX4:      "End.DX4",
+	SEG6_LOCAL_ACTION_END_DT6:      "End.DT6",
+	SEG6_LOCAL_ACTION_END_DT4:      "End.DT4",
+	SEG6_LOCAL_ACTION_END_B6:       "End.B6",
+	SEG6_LOCAL_ACTION_END_B6_ENCAP: "End.B6.Encaps",
+	SEG6_LOCAL_ACTION_END_BM:       "End.BM",
+	SEG6_LOCAL_ACTION_END_S:        "End.S",
+	SEG6_LOCAL_ACTION_END_AS:       "End.AS",
+	SEG6_LOCAL_ACTION_END_AM:       "End.AM",
+	SEG6_LOCAL_ACTION_END_BPF:      "End.BPF",
+	SEG6_LOCAL_ACTION_END_DT46:     "End.DT46",
+}
+
+// String returns the name of the action like iproute2, like `End.X`.
+func (a Seg6LocalAction) String() string {
+	if name, ok := seg6LocalActionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", a)
+}
+
+// ParseSeg6LocalAction parses a seg6local action from its name.
+func ParseSeg6LocalAction(s string) (Seg6LocalAction, error) {
+	for action, name := range seg6LocalActionNames {
+		if name == s {
+			return action, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid seg6local action \"%s\"", s)
+}
+
+// Seg6LocalCounters is the counters of a seg6local behavior.
+type Seg6LocalCounters struct {
+	Packets uint64
+	Bytes   uint64
+	Errors  uint64
+}
+
+// Seg6LocalEncap is a local SRv6 behavior of the route to a segment.
+// The fields besides Action are used by some actions only, like NH6
+// for End.X and Table for End.T. The counters are enabled if Counters
+// is not nil.
+type Seg6LocalEncap struct {
+	Action   Seg6LocalAction
+	Segments []net.IP
+	HMAC     uint32
+	Table    int
+	VRFTable int
+	NH4      net.IP
+	NH6      net.IP
+	Iif      int
+	Oif      int
+	BPF      *BPFProg
+	Counters *Seg6LocalCounters
+}
+
+// EncapType returns LWTUNNEL_ENCAP_SEG6_LOCAL.
+func (e *Seg6LocalEncap) EncapType() int { return unix.LWTUNNEL_ENCAP_SEG6_LOCAL }
+
+func (e *Seg6LocalEncap) encode(ae *netlink.AttributeEncoder) error {
+	ae.Uint32(SEG6_LOCAL_ACTION, uint32(e.Action))
+	if len(e.Segments) != 0 {
+		srh, err := marshalSRH(e.Segments, e.HMAC)
+		if err != nil {
+			return err
+		}
+		ae.Bytes(SEG6_LOCAL_SRH, srh)
+	}
+	if e.Table != 0 {
+		ae.Uint32(SEG6_LOCAL_TABLE, uint32(e.Table))
+	}
+	if e.VRFTable != 0 {
+		ae.Uint32(SEG6_LOCAL_VRFTABLE, uint32(e.VRFTable))
+	}
+	if e.NH4 != nil {
+		nh4 := e.NH4.To4()
+		if nh4 == nil {
+			return fmt.Errorf("seg6local: invalid nh4 %s", e.NH4)
+		}
+		ae.Bytes(SEG6_LOCAL_NH4, nh4)
+	}
+	if e.NH6 != nil {
+		ae.Bytes(SEG6_LOCAL_NH6, e.NH6.To16())
+	}
+	if e.Iif != 0 {
+		ae.Uint32(SEG6_LOCAL_IIF, uint32(e.Iif))
+	}
+	if e.Oif != 0 {
+		ae.Uint32(SEG6_LOCAL_OIF, uint32(e.Oif))
+	}
+	if e.BPF != nil {
+		ae.Nested(SEG6_LOCAL_BPF, e.BPF.encode)
+	}
+	if e.Counters != nil {
+		ae.Nested(SEG6_LOCAL_COUNTERS, e.Counters.encode)
+	}
+	return nil
+}
+
+// A BPFProg is a BPF program attached to a route. FD is used to attach
+// the program, and the kernel reports the Name only.
+type BPFProg struct {
+	FD   int
+	Name string
+}
+
+// encode encodes the program as the nested LWT_BPF_PROG_* attributes,
+// which are the same as SEG6_LOCAL_BPF_PROG_*.
+func (p *BPFProg) encode(ae *netlink.AttributeEncoder) error {
+	ae.Uint32(LWT_BPF_PROG_FD, uint32(p.FD))
+	ae.String(LWT_BPF_PROG_NAME, p.Name)
+	return nil
+}
+
+// decode decodes the nested LWT_BPF_PROG_* attributes.
+func (p *BPFProg) decode(ad *netlink.AttributeDecoder) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case LWT_BPF_PROG_FD:
+			p.FD = int(ad.Uint32())
+		case LWT_BPF_PROG_NAME:
+			p.Name = ad.String()
+		}
+	}
+	return nil
+}
+
+// BPFEncap runs the BPF programs at the input, output and xmit of the
+// route. Headroom is the bytes reserved by the xmit program.
+type BPFEncap struct {
+	In       *BPFProg
+	Out      *BPFProg
+	Xmit     *BPFProg
+	Headroom int
+}
+
+// EncapType returns LWTUNNEL_ENCAP_BPF.
+func (e *BPFEncap) EncapType() int { return unix.LWTUNNEL_ENCAP_BPF }
+
+func (e *BPFEncap) encode(ae *netlink.AttributeEncoder) error {
+	for _, p := range []struct {
+		typ  uint16
+		prog *BPFProg
+	}{
+		{LWT_BPF_IN, e.In},
+		{LWT_BPF_OUT, e.Out},
+		{LWT_BPF_XMIT, e.Xmit},
+	} {
+		if p.prog != nil {
+			ae.Nested(p.typ, p.prog.encode)
+		}
+	}
+	if e.Headroom != 0 {
+		ae.Uint32(LWT_BPF_XMIT_HEADROOM, uint32(e.Headroom))
+	}
+	return nil
+}
+
+// IOAM6Mode is the IOAM6_IPTUNNEL_MODE_* mode of the ioam6
+// encapsulation.
+type IOAM6Mode int
+
+// the ioam6 encapsulation modes.
+const (
+	IOAM6_IPTUNNEL_MODE_INLINE = 0x1
+	IOAM6_IPTUNNEL_MODE_ENCAP  = 0x2
+	IOAM6_IPTUNNEL_MODE_AUTO   = 0x3
+)
+
+var ioam6ModeNames = map[IOAM6Mode]string{
+	IOAM6_IPTUNNEL_MODE_INLINE: "inline",
+	IOAM6_IPTUNNEL_MODE_ENCAP:  "encap",
+	IOAM6_IPTUNNEL_MODE_AUTO:   "auto",
+}
+
+// String returns the name of the mode like iproute2.
+func (m IOAM6Mode) String() string {
+	if name, ok := ioam6ModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", m)
+}
+
+// ParseIOAM6Mode parses an ioam6 mode from its name, like `encap`.
+func ParseIOAM6Mode(s string) (IOAM6Mode, error) {
+	for mode, name := range ioam6ModeNames {
+		if name == s {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid ioam6 mode \"%s\"", s)
+}
+
+// IOAM6Encap inserts the IOAM pre-allocated trace option into k of
+// every n packets. TraceType is the 24 bits trace type, and Size is the
+// bytes pre-allocated for the node data. Dst is the tunnel destination
+// of the encap and auto modes.
+type IOAM6Encap struct {
+	Mode      IOAM6Mode
+	FreqK     uint32
+	FreqN     uint32
+	Dst       net.IP
+	Namespace uint16
+	TraceType uint32
+	Size      int
+}
+
+// EncapType returns LWTUNNEL_ENCAP_IOAM6.
+func (e *IOAM6Encap) EncapType() int { return unix.LWTUNNEL_ENCAP_IOAM6 }
+
+func (e *IOAM6Encap) encode(ae *netlink.AttributeEncoder) error {
+	if e.FreqK != 0 {
+		ae.Uint32(IOAM6_IPTUNNEL_FREQ_K, e.FreqK)
+	}
+	if e.FreqN != 0 {
+		ae.Uint32(IOAM6_IPTUNNEL_FREQ_N, e.FreqN)
+	}
+	if e.Mode != 0 {
+		ae.Uint8(IOAM6_IPTUNNEL_MODE, uint8(e.Mode))
+	}
+	if e.Dst != nil {
+		ae.Bytes(IOAM6_IPTUNNEL_DST, e.Dst.To16())
+	}
+
+	// struct ioam6_trace_hdr, whose remlen is in 4-octet units.
+	trace := make([]byte, 8)
+	binary.BigEndian.PutUint16(trace, e.Namespace)
+	trace[3] = uint8(e.Size / 4)
+	binary.BigEndian.PutUint32(trace[4:], e.TraceType<<8)
+	ae.Bytes(IOAM6_IPTUNNEL_TRACE, trace)
+	return nil
+}
+
+// RawEncap is an encapsulation this package does not decode, like ILA
+// and RPL. Data is the nested attributes of RTA_ENCAP.
+type RawEncap struct {
+	Type int
+	Data []byte
+}
+
+// EncapType returns the type of the encapsulation.
+func (e *RawEncap) EncapType() int { return e.Type }
+
+func (e *RawEncap) encode(ae *netlink.AttributeEncoder) error {
+	ad, err := netlink.NewAttributeDecoder(e.Data)
+	if err != nil {
+		return err
+	}
+	for ad.Next() {
+		ae.Bytes(ad.Type()|ad.TypeFlags(), ad.Bytes())
+	}
+	return ad.Err()
+}
+
+// encodeRouteEncap encodes the encapsulation as the typ attribute and
+// the nested encap attribute, which are RTA_ENCAP_TYPE and RTA_ENCAP for
+// routes.
+func encodeRouteEncap(ae *netlink.AttributeEncoder, encap RouteEncap, typ, encapAttr uint16) {
+	ae.Nested(encapAttr, encap.encode)
+	ae.Uint16(typ, uint16(encap.EncapType()))
+}
+
+// unmarshalRouteEncap unmarshals the nested attributes of RTA_ENCAP by
+// the type from RTA_ENCAP_TYPE.
+func unmarshalRouteEncap(typ int, data []byte) (RouteEncap, error) {
+	ad, err := netlink.NewAttributeDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var encap RouteEncap
+	switch typ {
+	case unix.LWTUNNEL_ENCAP_MPLS:
+		encap, err = decodeMPLSEncap(ad)
+	case unix.LWTUNNEL_ENCAP_IP, unix.LWTUNNEL_ENCAP_IP6:
+		encap, err = decodeIPEncap(ad, typ)
+	case unix.LWTUNNEL_ENCAP_SEG6:
+		encap, err = decodeSeg6Encap(ad)
+	case unix.LWTUNNEL_ENCAP_SEG6_LOCAL:
+		encap, err = decodeSeg6LocalEncap(ad)
+	case unix.LWTUNNEL_ENCAP_BPF:
+		encap, err = decodeBPFEncap(ad)
+	case unix.LWTUNNEL_ENCAP_IOAM6:
+		encap, err = decodeIOAM6Encap(ad)
+	default:
+		return &RawEncap{Type: typ, Data: data}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encap, ad.Err()
+}
+
+func decodeMPLSEncap(ad *netlink.AttributeDecoder) (RouteEncap, error) {
+	var e MPLSEncap
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.MPLS_IPTUNNEL_DST:
+			e.Labels = unmarshalMPLSLabels(ad.Bytes())
+		case unix.MPLS_IPTUNNEL_TTL:
+			e.TTL = int(ad.Uint8())
+		}
+	}
+	return &e, nil
+}
+
+func decodeIPEncap(ad *netlink.AttributeDecoder, typ int) (RouteEncap, error) {
+	var e IPEncap
+	e.Family = syscall.AF_INET
+	if typ == unix.LWTUNNEL_ENCAP_IP6 {
+		e.Family = syscall.AF_INET6
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case LWTUNNEL_IP_ID:
+			if b := ad.Bytes(); len(b) == 8 {
+				e.ID = binary.BigEndian.Uint64(b)
+			}
+		case LWTUNNEL_IP_DST:
+			e.Dst = net.IP(ad.Bytes())
+		case LWTUNNEL_IP_SRC:
+			e.Src = net.IP(ad.Bytes())
+		case LWTUNNEL_IP_TTL:
+			e.TTL = int(ad.Uint8())
+		case LWTUNNEL_IP_TOS:
+			e.Tos = int(ad.Uint8())
+		case LWTUNNEL_IP_FLAGS:
+			if b := ad.Bytes(); len(b) == 2 {
+				e.Flags = IPEncapFlags(binary.BigEndian.Uint16(b))
+			}
+		case LWTUNNEL_IP_OPTS:
+			e.Opts = ad.Bytes()
+		}
+	}
+	return &e, nil
+}
+
+func decodeSeg6Encap(ad *netlink.AttributeDecoder) (RouteEncap, error) {
+	var e Seg6Encap
+	for ad.Next() {
+		if ad.Type() != SEG6_IPTUNNEL_SRH {
+			continue
+		}
+
+		data := ad.Bytes()
+		if len(data) < 4 {
+			return nil, errors.New("seg6: not enough data to unmarshal")
+		}
+		e.Mode = Seg6Mode(nlenc.Int32(data[:4]))
+		var err error
+		e.Segments, e.HMAC, err = unmarshalSRH(data[4:])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+func decodeSeg6LocalEncap(ad *netlink.AttributeDecoder) (RouteEncap, error) {
+	var e Seg6LocalEncap
+	for ad.Next() {
+		switch ad.Type() {
+		case SEG6_LOCAL_ACTION:
+			e.Action = Seg6LocalAction(ad.Uint32())
+		case SEG6_LOCAL_SRH:
+			var err error
+			e.Segments, e.HMAC, err = unmarshalSRH(ad.Bytes())
+			if err != nil {
+				return nil, err
+			}
+		case SEG6_LOCAL_TABLE:
+			e.Table = int(ad.Uint32())
+		case SEG6_LOCAL_VRFTABLE:
+			e.VRFTable = int(ad.Uint32())
+		case SEG6_LOCAL_NH4:
+			e.NH4 = net.IP(ad.Bytes())
+		case SEG6_LOCAL_NH6:
+			e.NH6 = net.IP(ad.Bytes())
+		case SEG6_LOCAL_IIF:
+			e.Iif = int(ad.Uint32())
+		case SEG6_LOCAL_OIF:
+			e.Oif = int(ad.Uint32())
+		case SEG6_LOCAL_BPF:
+			e.BPF = new(BPFProg)
+			ad.Nested(e.BPF.decode)
+		case SEG6_LOCAL_COUNTERS:
+			e.Counters = new(Seg6LocalCounters)
+			ad.Nested(e.Counters.decode)
+		}
+	}
+	return &e, nil
+}
+
+// encode encodes the counters as the nested SEG6_LOCAL_CNT_* attributes,
+// which are all required by the kernel to enable the counters.
+func (c *Seg6LocalCounters) encode(ae *netlink.AttributeEncoder) error {
+	ae.Uint64(SEG6_LOCAL_CNT_PACKETS, c.Packets)
+	ae.Uint64(SEG6_LOCAL_CNT_BYTES, c.Bytes)
+	ae.Uint64(SEG6_LOCAL_CNT_ERRORS, c.Errors)
+	return nil
+}
+
+// decode decodes the nested SEG6_LOCAL_CNT_* attributes.
+func (c *Seg6LocalCounters) decode(ad *netlink.AttributeDecoder) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case SEG6_LOCAL_CNT_PACKETS:
+			c.Packets = ad.Uint64()
+		case SEG6_LOCAL_CNT_BYTES:
+			c.Bytes = ad.Uint64()
+		case SEG6_LOCAL_CNT_ERRORS:
+			c.Errors = ad.Uint64()
+		}
+	}
+	return nil
+}
+
+func decodeBPFEncap(ad *netlink.AttributeDecoder) (RouteEncap, error) {
+	var e BPFEncap
+	for ad.Next() {
+		var prog **BPFProg
+		switch ad.Type() {
+		case LWT_BPF_IN:
+			prog = &e.In
+		case LWT_BPF_OUT:
+			prog = &e.Out
+		case LWT_BPF_XMIT:
+			prog = &e.Xmit
+		case LWT_BPF_XMIT_HEADROOM:
+			e.Headroom = int(ad.Uint32())
+		}
+		if prog != nil {
+			*prog = new(BPFProg)
+			ad.Nested((*prog).decode)
+		}
+	}
+	return &e, nil
+}
+
+func decodeIOAM6Encap(ad *netlink.AttributeDecoder) (RouteEncap, error) {
+	var e IOAM6Encap
+	for ad.Next() {
+		switch ad.Type() {
+		case IOAM6_IPTUNNEL_MODE:
+			e.Mode = IOAM6Mode(ad.Uint8())
+		case IOAM6_IPTUNNEL_FREQ_K:
+			e.FreqK = ad.Uint32()
+		case IOAM6_IPTUNNEL_FREQ_N:
+			e.FreqN = ad.Uint32()
+		case IOAM6_IPTUNNEL_DST:
+			e.Dst = net.IP(ad.Bytes())
+		case IOAM6_IPTUNNEL_TRACE:
+			trace := ad.Bytes()
+			if len(trace) < 8 {
+				return nil, errors.New("ioam6: not enough data to unmarshal")
+			}
+			e.Namespace = binary.BigEndian.Uint16(trace)
+			e.Size = int(trace[3]&0x7f) * 4
+			e.TraceType = binary.BigEndian.Uint32(trace[4:]) >> 8
+		}
+	}
+	return &e, nil
+}
@@ -3,6 +3,7 @@ package ip
 import (
 	"github.com/Asphaltt/go-iproute2"
 	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
 )
 
 // A Client can manipulate ip netlink interface.
@@ -27,3 +28,40 @@ func NewWithConn(conn *netlink.Conn) *Client {
 	c.conn = conn
 	return &c
 }
+
+// enableStrictCheck enables NETLINK_GET_STRICT_CHK on the connection, and
+// returns the function restoring its previous state, as the connection may
+// be shared with the caller.
+func (c *Client) enableStrictCheck() (func(), error) {
+	enabled, err := c.strictCheck()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.conn.SetOption(netlink.GetStrictCheck, true); err != nil {
+		return nil, err
+	}
+	return func() {
+		if !enabled {
+			c.conn.SetOption(netlink.GetStrictCheck, false)
+		}
+	}, nil
+}
+
+// strictCheck reports whether NETLINK_GET_STRICT_CHK is enabled on the
+// connection.
+func (c *Client) strictCheck() (bool, error) {
+	rc, err := c.conn.SyscallConn()
+	if err != nil {
+		return false, err
+	}
+
+	var val int
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		val, serr = unix.GetsockoptInt(int(fd), unix.SOL_NETLINK, unix.NETLINK_GET_STRICT_CHK)
+	})
+	if err != nil {
+		return false, err
+	}
+	return val != 0, serr
+}
@@ -8,7 +8,6 @@ import (
 	"unsafe"
 
 	"github.com/Asphaltt/go-iproute2"
-	"github.com/Asphaltt/go-iproute2/internal/etc"
 	"github.com/mdlayher/netlink"
 	"golang.org/x/sys/unix"
 )
@@ -241,8 +240,16 @@ type LinkGroup int
 // String returns the string description of the LinkGroup.
 // The group information is from */etc/iproute2/group*.
 func (g LinkGroup) String() string {
-	groups, _ := etc.ReadGroup()
-	return groups[int(g)]
+	return LinkGroupNames.Format(int(g))
+}
+
+// ParseLinkGroup parses a link group from its name or number.
+func ParseLinkGroup(s string) (LinkGroup, error) {
+	id, ok := LinkGroupNames.ID(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid group \"%s\"", s)
+	}
+	return LinkGroup(id), nil
 }
 
 // A LinkEntry contains information for the link from kernel，
@@ -0,0 +1,286 @@
+package ip
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// RTM_NEWMULTICAST is the message type of the multicast address
+	// notification, which is sent by newer kernels.
+	RTM_NEWMULTICAST = 0x38
+)
+
+const (
+	procNetIgmp     = "/proc/net/igmp"
+	procNetIgmp6    = "/proc/net/igmp6"
+	procNetDevMcast = "/proc/net/dev_mcast"
+)
+
+// A MaddrEntry is a multicast address which a link is a member of.
+// Family is AF_PACKET for link-layer addresses, and Lladdr is used
+// instead of Addr.
+type MaddrEntry struct {
+	Family  int
+	Ifindex int
+	Addr    net.IP
+	Lladdr  net.HardwareAddr
+	Users   int
+	Static  bool
+}
+
+// ListMaddrs gets the link-layer, IPv4 and IPv6 multicast memberships
+// of all links.
+// The IP memberships are dumped by RTM_GETMULTICAST, and are read from
+// /proc/net/igmp and /proc/net/igmp6 when the kernel does not support
+// dumping them. The link-layer memberships are always read from
+// /proc/net/dev_mcast.
+func (c *Client) ListMaddrs() ([]*MaddrEntry, error) {
+	entries, err := readDevMcast()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, family := range []int{syscall.AF_INET, syscall.AF_INET6} {
+		ents, err := c.listMaddrs(family)
+		if err != nil {
+			if family == syscall.AF_INET {
+				ents, err = readIgmp()
+			} else {
+				ents, err = readIgmp6()
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return entries, err
+			}
+		}
+		entries = append(entries, ents...)
+	}
+	return entries, nil
+}
+
+func (c *Client) listMaddrs(family int) ([]*MaddrEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETMULTICAST
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ifamsg iproute2.IfAddrMsg
+	ifamsg.Family = uint8(family)
+	msg.Data, _ = ifamsg.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*MaddrEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_GETMULTICAST &&
+			msg.Header.Type != RTM_NEWMULTICAST {
+			continue
+		}
+
+		e, ok, err := parseMaddrMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok && e.Family == family {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// AddMaddr adds a static link-layer multicast address to the link.
+func (c *Client) AddMaddr(ifindex int, lladdr net.HardwareAddr) error {
+	return ioctlMaddr(unix.SIOCADDMULTI, ifindex, lladdr)
+}
+
+// DelMaddr deletes a static link-layer multicast address from the link.
+func (c *Client) DelMaddr(ifindex int, lladdr net.HardwareAddr) error {
+	return ioctlMaddr(unix.SIOCDELMULTI, ifindex, lladdr)
+}
+
+// ifreqHwaddr is the struct ifreq with ifr_hwaddr.
+type ifreqHwaddr struct {
+	Name   [unix.IFNAMSIZ]byte
+	Family uint16
+	Data   [14]byte
+	_      [8]byte
+}
+
+// ioctlMaddr modifies the link-layer multicast addresses of the link,
+// because there is no netlink interface for them.
+func ioctlMaddr(req uint, ifindex int, lladdr net.HardwareAddr) error {
+	var ifr ifreqHwaddr
+	if len(lladdr) == 0 || len(lladdr) > len(ifr.Data) {
+		return errors.New("maddr: invalid link-layer address")
+	}
+
+	ifi, err := net.InterfaceByIndex(ifindex)
+	if err != nil {
+		return err
+	}
+	copy(ifr.Name[:unix.IFNAMSIZ-1], ifi.Name)
+	ifr.Family = syscall.AF_UNSPEC
+	copy(ifr.Data[:], lladdr)
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// parseMaddrMsg parses a multicast address from a netlink message.
+func parseMaddrMsg(msg *netlink.Message) (*MaddrEntry, bool, error) {
+	var ifamsg iproute2.IfAddrMsg
+	if err := ifamsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e MaddrEntry
+	e.Family = int(ifamsg.Family)
+	e.Ifindex = int(ifamsg.Index)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofIfAddrMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFA_MULTICAST:
+			e.Addr = net.IP(ad.Bytes())
+		}
+	}
+	err = ad.Err()
+	return &e, err == nil && e.Addr != nil, err
+}
+
+// readDevMcast reads the link-layer multicast memberships from
+// /proc/net/dev_mcast, whose line is like:
+//
+//	2    eth0            1     0     01005e000001
+func readDevMcast() ([]*MaddrEntry, error) {
+	var entries []*MaddrEntry
+	err := readProcLines(procNetDevMcast, func(fields []string) {
+		if len(fields) < 5 {
+			return
+		}
+		ifindex, _ := strconv.Atoi(fields[0])
+		users, _ := strconv.Atoi(fields[2])
+		static, _ := strconv.Atoi(fields[3])
+		addr, err := hex.DecodeString(fields[4])
+		if err != nil {
+			return
+		}
+		entries = append(entries, &MaddrEntry{
+			Family:  syscall.AF_PACKET,
+			Ifindex: ifindex,
+			Lladdr:  net.HardwareAddr(addr),
+			Users:   users,
+			Static:  static != 0,
+		})
+	})
+	return entries, err
+}
+
+// readIgmp reads the IPv4 multicast memberships from /proc/net/igmp,
+// whose group lines follow the link line:
+//
+//	4	eth0      :     1      V3
+//					010000E0     1 0:00000000		0
+func readIgmp() ([]*MaddrEntry, error) {
+	var entries []*MaddrEntry
+	ifindex := 0
+	err := readProcLines(procNetIgmp, func(fields []string) {
+		if len(fields) == 0 || fields[0] == "Idx" {
+			return
+		}
+		if (len(fields) >= 3 && fields[2] == ":") ||
+			(len(fields) >= 2 && strings.HasSuffix(fields[1], ":")) {
+			ifindex, _ = strconv.Atoi(fields[0])
+			return
+		}
+		if len(fields) < 2 || len(fields[0]) != 8 {
+			return
+		}
+
+		// the group is the hex of the network order address
+		// printed as a native endian integer.
+		group, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return
+		}
+		users, _ := strconv.Atoi(fields[1])
+		addr := make(net.IP, net.IPv4len)
+		nlenc.NativeEndian().PutUint32(addr, uint32(group))
+		entries = append(entries, &MaddrEntry{
+			Family:  syscall.AF_INET,
+			Ifindex: ifindex,
+			Addr:    addr,
+			Users:   users,
+		})
+	})
+	return entries, err
+}
+
+// readIgmp6 reads the IPv6 multicast memberships from /proc/net/igmp6,
+// whose line is like:
+//
+//	4    eth0            ff020000000000000000000000000001     1 0000000C 0
+func readIgmp6() ([]*MaddrEntry, error) {
+	var entries []*MaddrEntry
+	err := readProcLines(procNetIgmp6, func(fields []string) {
+		if len(fields) < 4 {
+			return
+		}
+		ifindex, _ := strconv.Atoi(fields[0])
+		addr, err := hex.DecodeString(fields[2])
+		if err != nil || len(addr) != net.IPv6len {
+			return
+		}
+		users, _ := strconv.Atoi(fields[3])
+		entries = append(entries, &MaddrEntry{
+			Family:  syscall.AF_INET6,
+			Ifindex: ifindex,
+			Addr:    net.IP(addr),
+			Users:   users,
+		})
+	})
+	return entries, err
+}
+
+// readProcLines calls fn with the whitespace separated fields of every
+// line of the proc file.
+func readProcLines(file string, fn func(fields []string)) error {
+	fd, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		fn(strings.Fields(scanner.Text()))
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,165 @@
+package ip
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// RTAX_FEATURE_TCP_USEC_TS is the route feature to use microsecond
+// resolution TCP timestamps.
+const RTAX_FEATURE_TCP_USEC_TS = 0x10
+
+// RouteFeatures is the features of the route metrics.
+type RouteFeatures uint32
+
+// String returns the string description of the RouteFeatures.
+func (f RouteFeatures) String() string {
+	features := []struct {
+		feature uint32
+		name    string
+	}{
+		{unix.RTAX_FEATURE_ECN, "ecn"},
+		{RTAX_FEATURE_TCP_USEC_TS, "tcp_usec_ts"},
+	}
+
+	var s []string
+	fl := uint32(f)
+	for _, feature := range features {
+		if fl&feature.feature != 0 {
+			s = append(s, feature.name)
+			fl &= ^feature.feature
+		}
+	}
+	if fl != 0 {
+		s = append(s, fmt.Sprintf("%#x", fl))
+	}
+	return strings.Join(s, ",")
+}
+
+// RouteMetrics is the metrics of a route from the RTA_METRICS attribute.
+// A zero field means that the metric is not set. Lock has the bit
+// 1<<RTAX_* set for every locked metric, which is not changed by the
+// kernel, like PMTU discovery for the mtu.
+type RouteMetrics struct {
+	Lock             uint32
+	MTU              uint32
+	AdvMSS           uint32
+	Window           uint32
+	RTT              time.Duration
+	RTTVar           time.Duration
+	SSThresh         uint32
+	CWnd             uint32
+	InitCWnd         uint32
+	InitRWnd         uint32
+	Reordering       uint32
+	HopLimit         uint32
+	Features         RouteFeatures
+	RTOMin           time.Duration
+	QuickAck         uint32
+	CCAlgo           string
+	FastopenNoCookie uint32
+}
+
+// Locked reports whether the metric RTAX_* is locked.
+func (m *RouteMetrics) Locked(rtax int) bool {
+	return m.Lock&(1<<uint(rtax)) != 0
+}
+
+// the kernel keeps the rtt in 1/8 ms and the rttvar in 1/4 ms.
+const (
+	rttUnit    = time.Millisecond / 8
+	rttVarUnit = time.Millisecond / 4
+)
+
+// unmarshalRouteMetrics unmarshals the nested RTAX_* attributes of
+// RTA_METRICS.
+func unmarshalRouteMetrics(data []byte) (*RouteMetrics, error) {
+	ad, err := netlink.NewAttributeDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var m RouteMetrics
+	for ad.Next() {
+		if ad.Type() == unix.RTAX_CC_ALGO {
+			m.CCAlgo = ad.String()
+			continue
+		}
+
+		val := ad.Uint32()
+		switch ad.Type() {
+		case unix.RTAX_LOCK:
+			m.Lock = val
+		case unix.RTAX_MTU:
+			m.MTU = val
+		case unix.RTAX_WINDOW:
+			m.Window = val
+		case unix.RTAX_RTT:
+			m.RTT = time.Duration(val) * rttUnit
+		case unix.RTAX_RTTVAR:
+			m.RTTVar = time.Duration(val) * rttVarUnit
+		case unix.RTAX_SSTHRESH:
+			m.SSThresh = val
+		case unix.RTAX_CWND:
+			m.CWnd = val
+		case unix.RTAX_ADVMSS:
+			m.AdvMSS = val
+		case unix.RTAX_REORDERING:
+			m.Reordering = val
+		case unix.RTAX_HOPLIMIT:
+			m.HopLimit = val
+		case unix.RTAX_INITCWND:
+			m.InitCWnd = val
+		case unix.RTAX_FEATURES:
+			m.Features = RouteFeatures(val)
+		case unix.RTAX_RTO_MIN:
+			m.RTOMin = time.Duration(val) * time.Millisecond
+		case unix.RTAX_INITRWND:
+			m.InitRWnd = val
+		case unix.RTAX_QUICKACK:
+			m.QuickAck = val
+		case unix.RTAX_FASTOPEN_NO_COOKIE:
+			m.FastopenNoCookie = val
+		}
+	}
+	return &m, ad.Err()
+}
+
+// encode encodes the set metrics as the nested attributes of
+// RTA_METRICS.
+func (m *RouteMetrics) encode(ae *netlink.AttributeEncoder) error {
+	metrics := []struct {
+		typ uint16
+		val uint32
+	}{
+		{unix.RTAX_LOCK, m.Lock},
+		{unix.RTAX_MTU, m.MTU},
+		{unix.RTAX_WINDOW, m.Window},
+		{unix.RTAX_RTT, uint32(m.RTT / rttUnit)},
+		{unix.RTAX_RTTVAR, uint32(m.RTTVar / rttVarUnit)},
+		{unix.RTAX_SSTHRESH, m.SSThresh},
+		{unix.RTAX_CWND, m.CWnd},
+		{unix.RTAX_ADVMSS, m.AdvMSS},
+		{unix.RTAX_REORDERING, m.Reordering},
+		{unix.RTAX_HOPLIMIT, m.HopLimit},
+		{unix.RTAX_INITCWND, m.InitCWnd},
+		{unix.RTAX_FEATURES, uint32(m.Features)},
+		{unix.RTAX_RTO_MIN, uint32(m.RTOMin / time.Millisecond)},
+		{unix.RTAX_INITRWND, m.InitRWnd},
+		{unix.RTAX_QUICKACK, m.QuickAck},
+		{unix.RTAX_FASTOPEN_NO_COOKIE, m.FastopenNoCookie},
+	}
+	for _, metric := range metrics {
+		if metric.val != 0 {
+			ae.Uint32(metric.typ, metric.val)
+		}
+	}
+	if m.CCAlgo != "" {
+		ae.String(unix.RTAX_CC_ALGO, m.CCAlgo)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// RTNLGRP_STATS is the group of the link statistics, which is missing
+	// in golang.org/x/sys/unix.
+	RTNLGRP_STATS = 0x24
+
+	NETNSA_NSID = 0x1
+
+	PREFIX_ADDRESS   = 0x1
+	PREFIX_CACHEINFO = 0x2
+
+	IF_PREFIX_ONLINK   = 0x1
+	IF_PREFIX_AUTOCONF = 0x2
+)
+
+// MonitorObject is a set of the objects to monitor.
+type MonitorObject uint32
+
+const (
+	MonitorLink MonitorObject = 1 << iota
+	MonitorAddress
+	MonitorRoute
+	MonitorNeigh
+	MonitorRule
+	MonitorNexthop
+	MonitorNetconf
+	MonitorNSID
+	MonitorPrefix
+	MonitorStats
+
+	MonitorAll = MonitorLink | MonitorAddress | MonitorRoute | MonitorNeigh |
+		MonitorRule | MonitorNexthop | MonitorNetconf | MonitorNSID |
+		MonitorPrefix | MonitorStats
+)
+
+// monitorGroups are the multicast groups of the objects.
+var monitorGroups = []struct {
+	obj    MonitorObject
+	groups []uint32
+}{
+	{MonitorLink, []uint32{unix.RTNLGRP_LINK}},
+	{MonitorAddress, []uint32{unix.RTNLGRP_IPV4_IFADDR, unix.RTNLGRP_IPV6_IFADDR}},
+	{MonitorRoute, []uint32{unix.RTNLGRP_IPV4_ROUTE, unix.RTNLGRP_IPV6_ROUTE, unix.RTNLGRP_MPLS_ROUTE}},
+	{MonitorNeigh, []uint32{unix.RTNLGRP_NEIGH}},
+	{MonitorRule, []uint32{unix.RTNLGRP_IPV4_RULE, unix.RTNLGRP_IPV6_RULE}},
+	{MonitorNexthop, []uint32{unix.RTNLGRP_NEXTHOP}},
+	{MonitorNetconf, []uint32{unix.RTNLGRP_IPV4_NETCONF, unix.RTNLGRP_IPV6_NETCONF, unix.RTNLGRP_MPLS_NETCONF}},
+	{MonitorNSID, []uint32{unix.RTNLGRP_NSID}},
+	{MonitorPrefix, []uint32{unix.RTNLGRP_IPV6_PREFIX}},
+	{MonitorStats, []uint32{RTNLGRP_STATS}},
+}
+
+func (o MonitorObject) groups() []uint32 {
+	var groups []uint32
+	for _, g := range monitorGroups {
+		if o&g.obj != 0 {
+			groups = append(groups, g.groups...)
+		}
+	}
+	return groups
+}
+
+var monitorObjectNames = []string{
+	"link",
+	"address",
+	"route",
+	"neigh",
+	"rule",
+	"nexthop",
+	"netconf",
+	"nsid",
+	"prefix",
+	"stats",
+}
+
+func (o MonitorObject) String() string {
+	if o == MonitorAll {
+		return "all"
+	}
+	var names []string
+	for i, name := range monitorObjectNames {
+		if o&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseMonitorObject parses an object to monitor from its name, like
+// `route` or `all`.
+func ParseMonitorObject(s string) (MonitorObject, error) {
+	if s == "all" {
+		return MonitorAll, nil
+	}
+	for i, name := range monitorObjectNames {
+		if s == name {
+			return 1 << i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid monitor object \"%s\"", s)
+}
+
+// MonitorAction is what happened to the object of a monitor event.
+type MonitorAction int
+
+const (
+	MonitorActionAdd MonitorAction = iota
+	MonitorActionDel
+	MonitorActionChange
+	// MonitorActionOverrun is that the socket is overrun and some events
+	// are lost, and the event has no object.
+	MonitorActionOverrun
+)
+
+func (a MonitorAction) String() string {
+	switch a {
+	case MonitorActionAdd:
+		return "add"
+	case MonitorActionDel:
+		return "del"
+	case MonitorActionChange:
+		return "change"
+	case MonitorActionOverrun:
+		return "overrun"
+	}
+	return "unknown"
+}
+
+// A MonitorEvent is an object added, deleted or changed in the kernel.
+//
+// Object is one of *LinkEntry, *AddrEntry, *RouteEntry, *NeighEntry,
+// *RuleEntry, *NexthopEntry, *NetconfEntry, *NSIDEntry, *PrefixEntry and
+// *LinkStatsEntry, or nil for MonitorActionOverrun.
+//
+// The kernel doesn't tell the changes of some objects from the adding,
+// like the neighbours and the addresses, which are reported as added.
+// The links are changed unless they're new, and the other objects are
+// changed if they're replaced.
+type MonitorEvent struct {
+	Action MonitorAction
+	Object interface{}
+}
+
+// An NSIDEntry is the id of a peer network namespace.
+type NSIDEntry struct {
+	NSID int
+}
+
+// A PrefixEntry is an IPv6 prefix from a router advertisement.
+type PrefixEntry struct {
+	Family        int
+	Ifindex       int
+	Prefix        net.IP
+	PrefixLen     int
+	Type          int
+	OnLink        bool
+	Autoconf      bool
+	PreferredTime uint32
+	ValidTime     uint32
+}
+
+// A LinkStatsEntry is the notification of the statistics of a link, the
+// statistics are selected by FilterMask, like the offload statistics.
+type LinkStatsEntry struct {
+	Family     int
+	Ifindex    int
+	FilterMask uint32
+}
+
+// Monitor monitors the objects, and calls the handler for every event
+// until an error occurs, like the connection is closed. The overrun of
+// the socket doesn't stop monitoring like iproute2, but it's notified by
+// an event of MonitorActionOverrun, as some events are lost.
+func (c *Client) Monitor(objects MonitorObject, handler func(*MonitorEvent)) error {
+	groups := objects.groups()
+	for _, g := range groups {
+		if err := c.conn.JoinGroup(g); err != nil {
+			return err
+		}
+		defer c.conn.LeaveGroup(g)
+	}
+
+	for {
+		msgs, err := c.conn.Receive()
+		if errors.Is(err, unix.ENOBUFS) {
+			handler(&MonitorEvent{Action: MonitorActionOverrun})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			ev, ok, err := parseMonitorMsg(&msg)
+			if err != nil {
+				return err
+			}
+			if ok {
+				handler(ev)
+			}
+		}
+	}
+}
+
+// parseMonitorMsg parses the event from the notification message, the
+// messages of the unknown types are skipped.
+func parseMonitorMsg(msg *netlink.Message) (*MonitorEvent, bool, error) {
+	var ev MonitorEvent
+	if msg.Header.Flags&netlink.Replace != 0 {
+		ev.Action = MonitorActionChange
+	}
+
+	var obj interface{}
+	var ok bool
+	var err error
+	switch msg.Header.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		var ifimsg iproute2.IfInfoMsg
+		if err := ifimsg.UnmarshalBinary(msg.Data); err != nil {
+			return nil, false, err
+		}
+		// the change mask of a new link is all ones.
+		if ifimsg.Change != ^uint32(0) {
+			ev.Action = MonitorActionChange
+		}
+		obj, ok, err = parseLinkMsg(msg)
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		obj, ok, err = parseAddrMsg(msg)
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		obj, ok, err = parseRouteMsg(msg)
+	case iproute2.RTM_NEWNEIGH, iproute2.RTM_DELNEIGH:
+		obj, ok, err = parseNeighMsg(msg)
+	case unix.RTM_NEWRULE, unix.RTM_DELRULE:
+		obj, ok, err = parseRuleMsg(msg)
+	case unix.RTM_NEWNEXTHOP, unix.RTM_DELNEXTHOP:
+		obj, ok, err = parseNexthopMsg(msg)
+	case unix.RTM_NEWNETCONF, unix.RTM_DELNETCONF:
+		obj, ok, err = parseNetconfMsg(msg)
+	case unix.RTM_NEWNSID, unix.RTM_DELNSID:
+		obj, ok, err = parseNSIDMsg(msg)
+	case unix.RTM_NEWPREFIX:
+		obj, ok, err = parsePrefixMsg(msg)
+	case unix.RTM_NEWSTATS:
+		obj, ok, err = parseLinkStatsMsg(msg)
+	}
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	switch msg.Header.Type {
+	case unix.RTM_DELLINK, unix.RTM_DELADDR, unix.RTM_DELROUTE,
+		iproute2.RTM_DELNEIGH, unix.RTM_DELRULE, unix.RTM_DELNEXTHOP,
+		unix.RTM_DELNETCONF, unix.RTM_DELNSID:
+		ev.Action = MonitorActionDel
+	}
+	ev.Object = obj
+	return &ev, true, nil
+}
+
+// parseNSIDMsg parses a network namespace id from a netlink message, the
+// message begins with a struct rtgenmsg.
+func parseNSIDMsg(msg *netlink.Message) (*NSIDEntry, bool, error) {
+	offset := nlmsgAlign(1)
+	if len(msg.Data) < offset {
+		return nil, false, nil
+	}
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[offset:])
+	if err != nil {
+		return nil, false, err
+	}
+
+	e := NSIDEntry{NSID: -1}
+	for ad.Next() {
+		if ad.Type() == NETNSA_NSID {
+			e.NSID = int(ad.Int32())
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return nil, false, err
+	}
+	return &e, true, nil
+}
+
+// parsePrefixMsg parses an IPv6 prefix from a netlink message.
+func parsePrefixMsg(msg *netlink.Message) (*PrefixEntry, bool, error) {
+	var pm iproute2.PrefixMsg
+	if err := pm.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e PrefixEntry
+	e.Family = int(pm.Family)
+	e.Ifindex = int(pm.Ifindex)
+	e.PrefixLen = int(pm.Len)
+	e.Type = int(pm.Type)
+	e.OnLink = pm.Flags&IF_PREFIX_ONLINK != 0
+	e.Autoconf = pm.Flags&IF_PREFIX_AUTOCONF != 0
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofPrefixMsg:])
+	if err != nil {
+		return nil, false, err
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case PREFIX_ADDRESS:
+			e.Prefix = net.IP(ad.Bytes())
+		case PREFIX_CACHEINFO:
+			b := ad.Bytes()
+			if len(b) < 8 {
+				continue
+			}
+			e.PreferredTime = ad.ByteOrder.Uint32(b[0:4])
+			e.ValidTime = ad.ByteOrder.Uint32(b[4:8])
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return nil, false, err
+	}
+	return &e, true, nil
+}
+
+// parseLinkStatsMsg parses a link statistics notification from a netlink
+// message.
+func parseLinkStatsMsg(msg *netlink.Message) (*LinkStatsEntry, bool, error) {
+	var sm iproute2.IfStatsMsg
+	if err := sm.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+	return &LinkStatsEntry{
+		Family:     int(sm.Family),
+		Ifindex:    int(sm.Ifindex),
+		FilterMask: sm.FilterMask,
+	}, true, nil
+}
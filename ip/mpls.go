@@ -0,0 +1,106 @@
+package ip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	procSysNetMPLS = "/proc/sys/net/mpls"
+)
+
+// MPLSTTLPropagate is whether an MPLS route propagates the TTL of the
+// label to the IP header when popping the last label, which follows
+// net.mpls.ip_ttl_propagate by default.
+type MPLSTTLPropagate int
+
+// the TTL propagation of MPLS routes.
+const (
+	MPLS_TTL_PROP_DEFAULT  = 0x0
+	MPLS_TTL_PROP_ENABLED  = 0x1
+	MPLS_TTL_PROP_DISABLED = 0x2
+)
+
+// String returns the name of the TTL propagation like iproute2.
+func (p MPLSTTLPropagate) String() string {
+	switch p {
+	case MPLS_TTL_PROP_DEFAULT:
+		return "default"
+	case MPLS_TTL_PROP_ENABLED:
+		return "enabled"
+	case MPLS_TTL_PROP_DISABLED:
+		return "disabled"
+	default:
+		return fmt.Sprintf("%d", p)
+	}
+}
+
+// ParseMPLSTTLPropagate parses the TTL propagation from its name, like
+// `enabled`.
+func ParseMPLSTTLPropagate(s string) (MPLSTTLPropagate, error) {
+	for p := MPLSTTLPropagate(MPLS_TTL_PROP_DEFAULT); p <= MPLS_TTL_PROP_DISABLED; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid ttl-propagate \"%s\"", s)
+}
+
+// marshalMPLSLabels marshals the labels to the label stack entries,
+// with the bottom of stack bit set on the last one.
+func marshalMPLSLabels(labels []uint32) []byte {
+	data := make([]byte, 4*len(labels))
+	for i, label := range labels {
+		entry := label << 12
+		if i == len(labels)-1 {
+			entry |= 1 << 8
+		}
+		binary.BigEndian.PutUint32(data[4*i:], entry)
+	}
+	return data
+}
+
+// unmarshalMPLSLabels unmarshals the labels from the label stack
+// entries.
+func unmarshalMPLSLabels(data []byte) []uint32 {
+	var labels []uint32
+	for ; len(data) >= 4; data = data[4:] {
+		entry := binary.BigEndian.Uint32(data)
+		labels = append(labels, entry>>12)
+		if entry&(1<<8) != 0 {
+			break
+		}
+	}
+	return labels
+}
+
+// SetMPLSInput enables or disables receiving MPLS packets on the link.
+// There is no netlink interface to set it, so it's written to
+// net.mpls.conf.<dev>.input, and it can be read by GetNetconf with
+// AF_MPLS.
+func (c *Client) SetMPLSInput(ifindex int, enable bool) error {
+	ifi, err := net.InterfaceByIndex(ifindex)
+	if err != nil {
+		return err
+	}
+
+	val := "0"
+	if enable {
+		val = "1"
+	}
+	return writeSysctl(filepath.Join(procSysNetMPLS, "conf", ifi.Name, "input"), val)
+}
+
+// SetMPLSPlatformLabels sets the size of the MPLS label table by
+// net.mpls.platform_labels, and the labels beyond it are dropped.
+func (c *Client) SetMPLSPlatformLabels(n int) error {
+	return writeSysctl(filepath.Join(procSysNetMPLS, "platform_labels"), strconv.Itoa(n))
+}
+
+func writeSysctl(file, val string) error {
+	return os.WriteFile(file, []byte(val), 0644)
+}
@@ -0,0 +1,234 @@
+package ip
+
+import (
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// The families of the multicast routes in rtnetlink.
+const (
+	RTNL_FAMILY_IPMR  = 128
+	RTNL_FAMILY_IP6MR = 129
+)
+
+// A MrouteOutIf is an outgoing interface of a multicast route, the
+// packets are forwarded to it only when their TTL is greater than the
+// TTL threshold.
+type MrouteOutIf struct {
+	Ifindex int
+	TTL     int
+}
+
+// MrouteStats is the statistics of a multicast route.
+type MrouteStats struct {
+	Packets uint64
+	Bytes   uint64
+	// WrongIf is the number of the packets arrived on the interfaces
+	// other than the incoming one.
+	WrongIf uint64
+}
+
+// A MrouteEntry is an entry of the multicast forwarding cache, whose
+// Family is AF_INET or AF_INET6. InIfindex is 0 when the entry is
+// unresolved, which is also marked by RTNH_F_UNRESOLVED in Flags.
+type MrouteEntry struct {
+	Family    int
+	Table     RouteTable
+	Src       net.IP
+	Dst       net.IP
+	InIfindex int
+	OutIfs    []*MrouteOutIf
+	Flags     RouteFlags
+	Stats     *MrouteStats
+	// Age is the time since the entry is used last time.
+	Age time.Duration
+}
+
+// Resolved reports whether the entry is resolved by the multicast routing
+// daemon.
+func (e *MrouteEntry) Resolved() bool {
+	return int(e.Flags)&unix.RTNH_F_UNRESOLVED == 0
+}
+
+// MrouteFilter selects the multicast routes like the selectors of
+// iproute2. The zero value selects the IPv4 multicast routes in the
+// default table, which is the only table without the multicast policy
+// routing. Src and Dst select the routes inside the prefixes.
+type MrouteFilter struct {
+	Family    int
+	Table     RouteTable
+	AllTables bool
+	InIfindex int
+	Src       net.IP
+	SrcLen    int
+	Dst       net.IP
+	DstLen    int
+}
+
+// match reports whether the multicast route is selected by the filter.
+func (f *MrouteFilter) match(e *MrouteEntry) bool {
+	if !f.AllTables {
+		table := f.Table
+		if table == unix.RT_TABLE_UNSPEC {
+			table = unix.RT_TABLE_DEFAULT
+		}
+		if e.Table != table {
+			return false
+		}
+	}
+	if f.InIfindex != 0 && e.InIfindex != f.InIfindex {
+		return false
+	}
+	if f.Src != nil && !matchMrouteAddr(e.Src, f.Src, f.SrcLen) {
+		return false
+	}
+	if f.Dst != nil && !matchMrouteAddr(e.Dst, f.Dst, f.DstLen) {
+		return false
+	}
+	return true
+}
+
+// matchMrouteAddr reports whether the address is inside the prefix.
+func matchMrouteAddr(addr, prefix net.IP, prefixLen int) bool {
+	if addr == nil {
+		return false
+	}
+	if ip := prefix.To4(); ip != nil {
+		prefix = ip
+	}
+	if ip := addr.To4(); ip != nil {
+		addr = ip
+	}
+	if len(addr) != len(prefix) {
+		return false
+	}
+	ipnet := &net.IPNet{IP: prefix, Mask: net.CIDRMask(prefixLen, len(prefix)*8)}
+	return ipnet.Contains(addr)
+}
+
+// ListMroutes gets the multicast routes selected by the filter, which may
+// be nil to get the IPv4 multicast routes in the default table.
+func (c *Client) ListMroutes(filter *MrouteFilter) ([]*MrouteEntry, error) {
+	if filter == nil {
+		filter = &MrouteFilter{}
+	}
+
+	var rtmsg iproute2.RtMsg
+	rtmsg.Family = RTNL_FAMILY_IPMR
+	if filter.Family == syscall.AF_INET6 {
+		rtmsg.Family = RTNL_FAMILY_IP6MR
+	}
+
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETROUTE
+	msg.Header.Flags = netlink.Dump | netlink.Request
+	msg.Data, _ = rtmsg.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*MrouteEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWROUTE {
+			continue
+		}
+
+		e, ok, err := parseMrouteMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok && filter.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func parseMrouteMsg(msg *netlink.Message) (*MrouteEntry, bool, error) {
+	var rtmsg iproute2.RtMsg
+	if err := rtmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e MrouteEntry
+	switch rtmsg.Family {
+	case RTNL_FAMILY_IPMR:
+		e.Family = syscall.AF_INET
+	case RTNL_FAMILY_IP6MR:
+		e.Family = syscall.AF_INET6
+	default:
+		return nil, false, nil
+	}
+	if rtmsg.Type != unix.RTN_MULTICAST {
+		return nil, false, nil
+	}
+	e.Table = RouteTable(rtmsg.Table)
+	e.Flags = RouteFlags(rtmsg.Flags)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofRtMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.RTA_TABLE:
+			e.Table = RouteTable(ad.Uint32())
+		case unix.RTA_SRC:
+			e.Src = net.IP(ad.Bytes())
+		case unix.RTA_DST:
+			e.Dst = net.IP(ad.Bytes())
+		case unix.RTA_IIF:
+			e.InIfindex = int(ad.Uint32())
+		case unix.RTA_MULTIPATH:
+			e.OutIfs = unmarshalMrouteOutIfs(ad.Bytes())
+		case unix.RTA_MFC_STATS:
+			var stats iproute2.RtaMfcStats
+			if err := stats.UnmarshalBinary(ad.Bytes()); err != nil {
+				return &e, false, err
+			}
+			e.Stats = &MrouteStats{
+				Packets: stats.Packets,
+				Bytes:   stats.Bytes,
+				WrongIf: stats.WrongIf,
+			}
+		case unix.RTA_EXPIRES:
+			e.Age = time.Duration(ad.Uint64()) * clockTick
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return &e, false, err
+	}
+	return &e, true, nil
+}
+
+// unmarshalMrouteOutIfs unmarshals the outgoing interfaces of the
+// RTA_MULTIPATH attribute, whose rtnh_hops is the TTL threshold.
+func unmarshalMrouteOutIfs(data []byte) []*MrouteOutIf {
+	var oifs []*MrouteOutIf
+	for len(data) >= iproute2.SizeofRtNexthop {
+		var rtnh iproute2.RtNexthop
+		_ = rtnh.UnmarshalBinary(data)
+		if int(rtnh.Len) < iproute2.SizeofRtNexthop || int(rtnh.Len) > len(data) {
+			break
+		}
+
+		oifs = append(oifs, &MrouteOutIf{
+			Ifindex: int(rtnh.Ifindex),
+			TTL:     int(rtnh.Hops),
+		})
+
+		n := rtaAlign(int(rtnh.Len))
+		if n > len(data) {
+			break
+		}
+		data = data[n:]
+	}
+	return oifs
+}
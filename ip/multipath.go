@@ -0,0 +1,160 @@
+package ip
+
+import (
+	"errors"
+	"net"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+// A RouteVia is a gateway whose family may differ from the route's,
+// like an IPv6 gateway of an IPv4 route.
+type RouteVia struct {
+	Family int
+	Addr   net.IP
+}
+
+// unmarshalRouteVia unmarshals the struct rtvia.
+func unmarshalRouteVia(data []byte) (*RouteVia, error) {
+	if len(data) < 2 {
+		return nil, errors.New("RouteVia: not enough data to unmarshal")
+	}
+
+	var via RouteVia
+	via.Family = int(nlenc.Uint16(data[:2]))
+	via.Addr = net.IP(data[2:])
+	return &via, nil
+}
+
+// marshalBinary marshals the via to the struct rtvia.
+func (v *RouteVia) marshalBinary() []byte {
+	data := make([]byte, 2, 2+len(v.Addr))
+	nlenc.PutUint16(data, uint16(v.Family))
+	return append(data, v.Addr...)
+}
+
+// A RouteNextHop is one of the nexthops of a multipath route.
+// Weight is the rtnh_hops plus one, like iproute2 shows.
+type RouteNextHop struct {
+	Ifindex int
+	Weight  int
+	Flags   RouteFlags
+	Gateway net.IP
+	Via     *RouteVia
+	Realms  uint32
+	Encap   RouteEncap
+	NewDst  []uint32
+}
+
+// unmarshalMultipath unmarshals the nexthops of the RTA_MULTIPATH
+// attribute, which is a list of struct rtnexthop followed by their
+// attributes.
+func unmarshalMultipath(data []byte) ([]*RouteNextHop, error) {
+	var nhs []*RouteNextHop
+	for len(data) >= iproute2.SizeofRtNexthop {
+		var rtnh iproute2.RtNexthop
+		_ = rtnh.UnmarshalBinary(data)
+		if int(rtnh.Len) < iproute2.SizeofRtNexthop || int(rtnh.Len) > len(data) {
+			return nhs, errors.New("RouteNextHop: invalid rtnexthop length")
+		}
+
+		var nh RouteNextHop
+		nh.Ifindex = int(rtnh.Ifindex)
+		nh.Weight = int(rtnh.Hops) + 1
+		nh.Flags = RouteFlags(rtnh.Flags)
+
+		var encapType int
+		var encap []byte
+
+		ad, err := netlink.NewAttributeDecoder(data[iproute2.SizeofRtNexthop:rtnh.Len])
+		if err != nil {
+			return nhs, err
+		}
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.RTA_GATEWAY:
+				nh.Gateway = net.IP(ad.Bytes())
+			case unix.RTA_VIA:
+				nh.Via, err = unmarshalRouteVia(ad.Bytes())
+				if err != nil {
+					return nhs, err
+				}
+			case unix.RTA_FLOW:
+				nh.Realms = ad.Uint32()
+			case unix.RTA_NEWDST:
+				nh.NewDst = unmarshalMPLSLabels(ad.Bytes())
+			case unix.RTA_ENCAP_TYPE:
+				encapType = int(ad.Uint16())
+			case unix.RTA_ENCAP:
+				encap = ad.Bytes()
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return nhs, err
+		}
+		if encap != nil {
+			if nh.Encap, err = unmarshalRouteEncap(encapType, encap); err != nil {
+				return nhs, err
+			}
+		}
+		nhs = append(nhs, &nh)
+
+		n := rtaAlign(int(rtnh.Len))
+		if n > len(data) {
+			break
+		}
+		data = data[n:]
+	}
+	return nhs, nil
+}
+
+// marshalMultipath marshals the nexthops to the RTA_MULTIPATH attribute.
+func marshalMultipath(family int, nhs []*RouteNextHop) ([]byte, error) {
+	var data []byte
+	for _, nh := range nhs {
+		ae := netlink.NewAttributeEncoder()
+		if nh.Gateway != nil {
+			gw, err := familyAddr(family, nh.Gateway)
+			if err != nil {
+				return nil, err
+			}
+			ae.Bytes(unix.RTA_GATEWAY, gw)
+		}
+		if nh.Via != nil {
+			ae.Bytes(unix.RTA_VIA, nh.Via.marshalBinary())
+		}
+		if nh.Realms != 0 {
+			ae.Uint32(unix.RTA_FLOW, nh.Realms)
+		}
+		if len(nh.NewDst) != 0 {
+			ae.Bytes(unix.RTA_NEWDST, marshalMPLSLabels(nh.NewDst))
+		}
+		if nh.Encap != nil {
+			encodeRouteEncap(ae, nh.Encap, unix.RTA_ENCAP_TYPE, unix.RTA_ENCAP)
+		}
+		attrs, err := ae.Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		var rtnh iproute2.RtNexthop
+		rtnh.Len = uint16(iproute2.SizeofRtNexthop + len(attrs))
+		rtnh.Flags = uint8(nh.Flags)
+		if nh.Weight > 0 {
+			rtnh.Hops = uint8(nh.Weight - 1)
+		}
+		rtnh.Ifindex = int32(nh.Ifindex)
+		b, _ := rtnh.MarshalBinary()
+		data = append(data, b...)
+		data = append(data, attrs...)
+	}
+	return data, nil
+}
+
+// rtaAlign aligns the length to the route attribute alignment.
+func rtaAlign(length int) int {
+	return (length + unix.RTA_ALIGNTO - 1) & ^(unix.RTA_ALIGNTO - 1)
+}
@@ -0,0 +1,205 @@
+package ip
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Asphaltt/go-iproute2/etc"
+	"golang.org/x/sys/unix"
+)
+
+// A NameTable maps the ids to their names, like the route tables in
+// */etc/iproute2/rt_tables*. It's safe for concurrent use.
+//
+// The names are loaded from the configuration file at the first lookup,
+// and kept until Reload. The built-in names are overridden by the ones
+// in the file, and both of them are overridden by the registered ones.
+type NameTable struct {
+	mu         sync.RWMutex
+	read       func() (map[int]string, error)
+	builtin    map[int]string
+	registered map[int]string
+	loaded     bool
+	names      map[int]string
+	ids        map[string]int
+}
+
+// NewNameTable creates a name table with the built-in names, which loads
+// the other names by read. read may be nil if there is no configuration
+// file.
+func NewNameTable(builtin map[int]string, read func() (map[int]string, error)) *NameTable {
+	return &NameTable{
+		read:       read,
+		builtin:    builtin,
+		registered: make(map[int]string),
+	}
+}
+
+// The name tables used by the String methods and the Parse functions.
+var (
+	RouteTableNames = NewNameTable(map[int]string{
+		unix.RT_TABLE_DEFAULT: "default",
+		unix.RT_TABLE_MAIN:    "main",
+		unix.RT_TABLE_LOCAL:   "local",
+	}, etc.ReadRouteTables)
+	RouteProtocolNames = NewNameTable(map[int]string{
+		unix.RTPROT_UNSPEC:     "unspec",
+		unix.RTPROT_REDIRECT:   "redirect",
+		unix.RTPROT_KERNEL:     "kernel",
+		unix.RTPROT_BOOT:       "boot",
+		unix.RTPROT_STATIC:     "static",
+		unix.RTPROT_GATED:      "gated",
+		unix.RTPROT_RA:         "ra",
+		unix.RTPROT_MRT:        "mrt",
+		unix.RTPROT_ZEBRA:      "zebra",
+		unix.RTPROT_BIRD:       "bird",
+		unix.RTPROT_BABEL:      "babel",
+		unix.RTPROT_DNROUTED:   "dnrouted",
+		unix.RTPROT_XORP:       "xorp",
+		unix.RTPROT_NTK:        "ntk",
+		unix.RTPROT_DHCP:       "dhcp",
+		unix.RTPROT_KEEPALIVED: "keepalived",
+		unix.RTPROT_BGP:        "bgp",
+		unix.RTPROT_ISIS:       "isis",
+		unix.RTPROT_OSPF:       "ospf",
+		unix.RTPROT_RIP:        "rip",
+		unix.RTPROT_EIGRP:      "eigrp",
+	}, etc.ReadRouteProtos)
+	RouteScopeNames = NewNameTable(map[int]string{
+		unix.RT_SCOPE_UNIVERSE: "global",
+		unix.RT_SCOPE_NOWHERE:  "nowhere",
+		unix.RT_SCOPE_HOST:     "host",
+		unix.RT_SCOPE_LINK:     "link",
+		unix.RT_SCOPE_SITE:     "site",
+	}, etc.ReadRouteScopes)
+	RealmNames = NewNameTable(map[int]string{
+		0: "unknown",
+	}, etc.ReadRealms)
+	DSFieldNames   = NewNameTable(nil, etc.ReadDSField)
+	LinkGroupNames = NewNameTable(map[int]string{
+		0: "default",
+	}, etc.ReadGroup)
+	AddrProtocolNames = NewNameTable(map[int]string{
+		int(AddrProtoUnspec):   "unspec",
+		int(AddrProtoKernelLo): "kernel_lo",
+		int(AddrProtoKernelRA): "kernel_ra",
+		int(AddrProtoKernelLL): "kernel_ll",
+	}, etc.ReadAddrProtos)
+)
+
+// nameTables are all the name tables to reload by ReloadNames.
+var nameTables = []*NameTable{
+	RouteTableNames,
+	RouteProtocolNames,
+	RouteScopeNames,
+	RealmNames,
+	DSFieldNames,
+	LinkGroupNames,
+	AddrProtocolNames,
+}
+
+// ReloadNames reloads all the name tables from their configuration files,
+// and returns the first error.
+func ReloadNames() error {
+	var firstErr error
+	for _, t := range nameTables {
+		if err := t.Reload(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Name gets the name of the id.
+func (t *NameTable) Name(id int) (string, bool) {
+	t.load()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	name, ok := t.names[id]
+	return name, ok
+}
+
+// Format gets the name of the id, or the id in decimal if it has no name.
+func (t *NameTable) Format(id int) string {
+	if name, ok := t.Name(id); ok {
+		return name
+	}
+	return strconv.Itoa(id)
+}
+
+// ID looks up the id of the name, and the name can also be a decimal or
+// hexadecimal number.
+func (t *NameTable) ID(name string) (int, bool) {
+	t.load()
+
+	t.mu.RLock()
+	id, ok := t.ids[name]
+	t.mu.RUnlock()
+	if ok {
+		return id, true
+	}
+
+	n, err := strconv.ParseUint(name, 0, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// Register adds the name of the id, which overrides the name from the
+// configuration file and is kept after Reload.
+func (t *NameTable) Register(id int, name string) {
+	t.load()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.registered[id] = name
+	t.set(id, name)
+}
+
+// Reload reloads the names from the configuration file. A missing file is
+// not an error, the built-in and the registered names are kept then.
+func (t *NameTable) Reload() error {
+	var names map[int]string
+	var err error
+	if t.read != nil {
+		names, err = t.read()
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.names = make(map[int]string, len(t.builtin)+len(names)+len(t.registered))
+	t.ids = make(map[string]int, len(t.builtin)+len(names)+len(t.registered))
+	for _, m := range []map[int]string{t.builtin, names, t.registered} {
+		for id, name := range m {
+			t.set(id, name)
+		}
+	}
+	t.loaded = true
+	return err
+}
+
+// set sets the name of the id, and drops the old name of the id.
+func (t *NameTable) set(id int, name string) {
+	if old, ok := t.names[id]; ok && t.ids[old] == id {
+		delete(t.ids, old)
+	}
+	t.names[id] = name
+	t.ids[name] = id
+}
+
+// load loads the names at the first time.
+func (t *NameTable) load() {
+	t.mu.RLock()
+	loaded := t.loaded
+	t.mu.RUnlock()
+	if !loaded {
+		_ = t.Reload()
+	}
+}
@@ -1,20 +1,122 @@
 package ip
 
 import (
+	"errors"
 	"net"
 	"syscall"
-	"unsafe"
+	"time"
 
 	"github.com/Asphaltt/go-iproute2"
 	"github.com/mdlayher/netlink"
 )
 
+// NeighStateNone selects the neighbours of no state in NeighFilter.State,
+// which is NUD_NONE and can't be a mask.
+const NeighStateNone iproute2.NudState = 0x100
+
+// NeighStateAll selects the neighbours of all states in NeighFilter.State.
+const NeighStateAll iproute2.NudState = 0xffff
+
 // A NeighEntry contains information for the arp records from kernel.
+// Probes is -1 when it's not given by the kernel.
+//
+// When adding a neighbour, the flags router, extern_learn, use and proxy,
+// and the extended flag managed are set by Flags and FlagsExt. A proxy
+// neighbour has neither Lladdr nor State.
+//
+// Master is only given by the kernel for the bridge fdb entries, it's not
+// filled for the ARP and NDP neighbours.
 type NeighEntry struct {
+	Family    int
+	Ifindex   int
+	Addr      net.IP
+	Lladdr    net.HardwareAddr
+	State     iproute2.NudState
+	Flags     iproute2.NtfFlag
+	FlagsExt  iproute2.NtfExtFlag
+	Type      RouteType
+	CacheInfo *NeighCacheInfo
+	Probes    int
+	Vlan      int
+	Protocol  RouteProtocol
+	NexthopID uint32
+	Master    int
+}
+
+// NeighCacheInfo is the cache information of a neighbour, the times are
+// the ages since the neighbour is confirmed, used and updated.
+type NeighCacheInfo struct {
+	Confirmed time.Duration
+	Used      time.Duration
+	Updated   time.Duration
+	RefCount  int
+}
+
+// NeighFilter selects the neighbours like the selectors of iproute2.
+// The zero value selects the IPv4 and IPv6 neighbours of all states
+// except noarp and none, like `ip neigh show`.
+//
+// State is a mask of the states, with NeighStateNone for the neighbours
+// of no state, and NeighStateAll for all of them. Proxy selects the proxy
+// neighbours instead, and Unused selects the neighbours not referenced.
+type NeighFilter struct {
+	Family  int
 	Ifindex int
-	Addr    net.IP
-	Lladdr  net.HardwareAddr
+	Master  int
 	State   iproute2.NudState
+	Proxy   bool
+	Unused  bool
+	Dst     net.IP
+	DstLen  int
+}
+
+// state gets the states selected by the filter.
+func (f *NeighFilter) state() iproute2.NudState {
+	if f.State == 0 {
+		return 0xff &^ iproute2.NudNoArp
+	}
+	return f.State
+}
+
+// match reports whether the neighbour is selected by the filter, which
+// is the same as iproute2.
+func (f *NeighFilter) match(e *NeighEntry) bool {
+	if f.Family != syscall.AF_UNSPEC && e.Family != f.Family {
+		return false
+	}
+	if f.Ifindex != 0 && e.Ifindex != f.Ifindex {
+		return false
+	}
+
+	// the proxy and the externally learned neighbours are always
+	// selected by the states.
+	state := f.state()
+	if state&e.State == 0 &&
+		e.Flags&(iproute2.NtfProxy|iproute2.NtfExtLearned) == 0 &&
+		(e.State != iproute2.NudNone || state&NeighStateNone == 0) {
+		return false
+	}
+
+	if f.Dst != nil {
+		if e.Addr == nil {
+			return false
+		}
+		dst := f.Dst
+		if ip := dst.To4(); ip != nil {
+			dst = ip
+		}
+		if len(dst) != len(e.Addr) {
+			return false
+		}
+		prefix := &net.IPNet{IP: dst, Mask: net.CIDRMask(f.DstLen, len(dst)*8)}
+		if !prefix.Contains(e.Addr) {
+			return false
+		}
+	}
+	if f.Unused && e.CacheInfo != nil && e.CacheInfo.RefCount != 0 {
+		return false
+	}
+	return true
 }
 
 // ListNeighbours dumps arp table from kernel.
@@ -22,14 +124,43 @@ type NeighEntry struct {
 // response messages. Secondly, parse neighbour information from every
 // netlink response messages one by one.
 func (c *Client) ListNeighbours() ([]*NeighEntry, error) {
+	return c.ListNeighboursWithFilter(&NeighFilter{State: NeighStateAll})
+}
+
+// ListNeighboursWithFilter gets the neighbours selected by the filter,
+// which may be nil to get the neighbours like `ip neigh show`.
+//
+// The kernel filters the neighbours by the device and the master, and
+// the others are filtered here, except the master, which the kernel
+// doesn't report for the neighbours.
+func (c *Client) ListNeighboursWithFilter(filter *NeighFilter) ([]*NeighEntry, error) {
+	if filter == nil {
+		filter = &NeighFilter{}
+	}
+
 	var ndmsg iproute2.NdMsg
-	ndmsg.Family = syscall.AF_UNSPEC
-	// ndmsg.State = uint16(0xFF & ^iproute2.NudNoArp)
+	ndmsg.Family = uint8(filter.Family)
+	if filter.Proxy {
+		ndmsg.Flags = uint8(iproute2.NtfProxy)
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	if filter.Ifindex != 0 {
+		ae.Uint32(uint16(iproute2.NdaIfindex), uint32(filter.Ifindex))
+	}
+	if filter.Master != 0 {
+		ae.Uint32(uint16(iproute2.NdaMaster), uint32(filter.Master))
+	}
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
 
 	var msg netlink.Message
 	msg.Header.Type = iproute2.RTM_GETNEIGH
 	msg.Header.Flags = netlink.Dump | netlink.Request
 	msg.Data, _ = ndmsg.MarshalBinary()
+	msg.Data = append(msg.Data, attrs...)
 
 	msgs, err := c.conn.Execute(msg)
 	if err != nil {
@@ -41,38 +172,306 @@ func (c *Client) ListNeighbours() ([]*NeighEntry, error) {
 		if msg.Header.Type != iproute2.RTM_NEWNEIGH {
 			continue
 		}
-		if msg.Header.Length < uint32(iproute2.SizeofNdMsg) {
-			continue
+
+		e, ok, err := parseNeighMsg(&msg)
+		if err != nil {
+			return entries, err
 		}
+		if ok && filter.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
 
-		ndmsg := (*iproute2.NdMsg)(unsafe.Pointer(&msg.Data[0]))
-		if ndmsg.Family != syscall.AF_INET &&
-			ndmsg.Family != syscall.AF_INET6 {
-			continue
+// parseNeighMsg parses the IPv4 and IPv6 neighbour from the message, the
+// neighbours of the other families are skipped.
+func parseNeighMsg(msg *netlink.Message) (*NeighEntry, bool, error) {
+	if len(msg.Data) < iproute2.SizeofNdMsg {
+		return nil, false, nil
+	}
+
+	var ndmsg iproute2.NdMsg
+	if err := ndmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+	if ndmsg.Family != syscall.AF_INET &&
+		ndmsg.Family != syscall.AF_INET6 {
+		return nil, false, nil
+	}
+
+	var e NeighEntry
+	e.Family = int(ndmsg.Family)
+	e.Ifindex = int(ndmsg.Ifindex)
+	e.State = iproute2.NudState(ndmsg.State)
+	e.Flags = iproute2.NtfFlag(ndmsg.Flags)
+	e.Type = RouteType(ndmsg.Type)
+	e.Probes = -1
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNdMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+	for ad.Next() {
+		switch iproute2.NdAttrType(ad.Type()) {
+		case iproute2.NdaDst:
+			e.Addr = net.IP(ad.Bytes())
+		case iproute2.NdaLladdr:
+			e.Lladdr = net.HardwareAddr(ad.Bytes())
+		case iproute2.NdaCacheInfo:
+			var ci iproute2.NdAttrCacheInfo
+			if err := ci.UnmarshalBinary(ad.Bytes()); err != nil {
+				return &e, false, err
+			}
+			e.CacheInfo = &NeighCacheInfo{
+				Confirmed: time.Duration(ci.Confirmed) * clockTick,
+				Used:      time.Duration(ci.Used) * clockTick,
+				Updated:   time.Duration(ci.Updated) * clockTick,
+				RefCount:  int(ci.RefCount),
+			}
+		case iproute2.NdaProbes:
+			e.Probes = int(ad.Uint32())
+		case iproute2.NdaVlan:
+			e.Vlan = int(ad.Uint16())
+		case iproute2.NdaProtocol:
+			e.Protocol = RouteProtocol(ad.Uint8())
+		case iproute2.NdaNhID:
+			e.NexthopID = ad.Uint32()
+		case iproute2.NdaMaster:
+			e.Master = int(ad.Uint32())
+		case iproute2.NdaFlagsExt:
+			e.FlagsExt = iproute2.NtfExtFlag(ad.Uint32())
 		}
+	}
+	if err := ad.Err(); err != nil {
+		return &e, false, err
+	}
+	return &e, true, nil
+}
+
+// AddNeighbour adds a neighbour to kernel, and fails if the neighbour
+// exists.
+func (c *Client) AddNeighbour(e *NeighEntry) error {
+	return c.modifyNeighbour(iproute2.RTM_NEWNEIGH, netlink.Create|netlink.Excl, e)
+}
+
+// DelNeighbour deletes a neighbour from kernel.
+func (c *Client) DelNeighbour(e *NeighEntry) error {
+	return c.modifyNeighbour(iproute2.RTM_DELNEIGH, 0, e)
+}
+
+// ReplaceNeighbour replaces a neighbour, or adds it if the neighbour does
+// not exist.
+func (c *Client) ReplaceNeighbour(e *NeighEntry) error {
+	return c.modifyNeighbour(iproute2.RTM_NEWNEIGH, netlink.Create|netlink.Replace, e)
+}
+
+// ChangeNeighbour changes an existing neighbour.
+func (c *Client) ChangeNeighbour(e *NeighEntry) error {
+	return c.modifyNeighbour(iproute2.RTM_NEWNEIGH, netlink.Replace, e)
+}
+
+func (c *Client) modifyNeighbour(typ netlink.HeaderType, flags netlink.HeaderFlags, e *NeighEntry) error {
+	var msg netlink.Message
+	msg.Header.Type = typ
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge | flags
+
+	data, err := marshalNeighMsg(e)
+	if err != nil {
+		return err
+	}
+	msg.Data = data
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+const (
+	// neighFlushRounds is the same as iproute2, the neighbours may be
+	// re-added when flushing.
+	neighFlushRounds = 10
+	// neighFlushBatch is the number of the delete requests sent in one
+	// sendmsg.
+	neighFlushBatch = 128
+)
 
-		ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNdMsg:])
+// FlushNeighbours deletes the neighbours selected by the filter, and
+// returns the number of the deleted ones. The filter may be nil to delete
+// the neighbours of all devices.
+//
+// Like iproute2, the permanent neighbours are not deleted unless the
+// states are given by the filter. The neighbours are dumped again until
+// none of them is selected, at most 10 rounds.
+func (c *Client) FlushNeighbours(filter *NeighFilter) (int, error) {
+	f := NeighFilter{}
+	if filter != nil {
+		f = *filter
+	}
+	if f.State == 0 {
+		f.State = 0xff&^(iproute2.NudNoArp|iproute2.NudPermanent) | NeighStateNone
+	}
+
+	n := 0
+	for round := 0; round < neighFlushRounds; round++ {
+		entries, err := c.ListNeighboursWithFilter(&f)
 		if err != nil {
-			return entries, err
+			return n, err
 		}
 
-		var e NeighEntry
-		e.Ifindex = int(ndmsg.Ifindex)
-		e.State = iproute2.NudState(ndmsg.State)
+		var msgs []netlink.Message
+		for _, e := range entries {
+			data, err := marshalNeighMsg(e)
+			if err != nil {
+				return n, err
+			}
+			msgs = append(msgs, netlink.Message{
+				Header: netlink.Header{
+					Type:  iproute2.RTM_DELNEIGH,
+					Flags: netlink.Request | netlink.Acknowledge,
+				},
+				Data: data,
+			})
+		}
+		if len(msgs) == 0 {
+			return n, nil
+		}
+
+		// keep deleting the others if some neighbours fail to be deleted.
+		var firstErr error
+		for len(msgs) != 0 {
+			batch := msgs
+			if len(batch) > neighFlushBatch {
+				batch = batch[:neighFlushBatch]
+			}
+			msgs = msgs[len(batch):]
 
-		for ad.Next() {
-			switch iproute2.NdAttrType(ad.Type()) {
-			case iproute2.NdaDst:
-				e.Addr = net.IP(ad.Bytes())
-			case iproute2.NdaLladdr:
-				e.Lladdr = net.HardwareAddr(ad.Bytes())
+			deleted, err := c.sendBatch(batch)
+			n += deleted
+			if err != nil && firstErr == nil {
+				firstErr = err
 			}
 		}
-		if err := ad.Err(); err != nil {
-			return entries, err
+		if firstErr != nil {
+			return n, firstErr
+		}
+	}
+	return n, errors.New("neighbour: flush remains incomplete after 10 rounds")
+}
+
+// marshalNeighMsg marshals a neighbour to the data of a netlink message.
+// The family is guessed by Addr if it's not set.
+func marshalNeighMsg(e *NeighEntry) ([]byte, error) {
+	if e.Addr == nil {
+		return nil, errors.New("neighbour: destination address is required")
+	}
+
+	family := e.Family
+	if family == syscall.AF_UNSPEC {
+		family = syscall.AF_INET6
+		if e.Addr.To4() != nil {
+			family = syscall.AF_INET
 		}
+	}
+	dst, err := familyAddr(family, e.Addr)
+	if err != nil {
+		return nil, errors.New("neighbour: address family mismatch")
+	}
+
+	var ndmsg iproute2.NdMsg
+	ndmsg.Family = uint8(family)
+	ndmsg.Ifindex = int32(e.Ifindex)
+	ndmsg.State = uint16(e.State)
+	ndmsg.Flags = uint8(e.Flags)
+	ndmsg.Type = uint8(e.Type)
 
-		entries = append(entries, &e)
+	ae := netlink.NewAttributeEncoder()
+	ae.Bytes(uint16(iproute2.NdaDst), dst)
+	if e.Lladdr != nil {
+		ae.Bytes(uint16(iproute2.NdaLladdr), e.Lladdr)
 	}
-	return entries, nil
+	if e.Protocol != 0 {
+		ae.Uint8(uint16(iproute2.NdaProtocol), uint8(e.Protocol))
+	}
+	if e.Vlan != 0 {
+		ae.Uint16(uint16(iproute2.NdaVlan), uint16(e.Vlan))
+	}
+	if e.NexthopID != 0 {
+		ae.Uint32(uint16(iproute2.NdaNhID), e.NexthopID)
+	}
+	if e.FlagsExt != 0 {
+		ae.Uint32(uint16(iproute2.NdaFlagsExt), uint32(e.FlagsExt))
+	}
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := ndmsg.MarshalBinary()
+	return append(data, attrs...), nil
+}
+
+// NeighGetOptions is the optional settings of a neighbour lookup.
+type NeighGetOptions struct {
+	// Proxy looks up the proxy neighbour instead.
+	Proxy bool
+	// Resolve triggers the resolution of the neighbour before the lookup,
+	// and the neighbour is created if it does not exist. The resolution
+	// is asynchronous, so the neighbour may be still incomplete.
+	Resolve bool
+}
+
+// GetNeighbour gets the neighbour of the address on the device from the
+// kernel. The device is optional for a proxy neighbour.
+func (c *Client) GetNeighbour(dst net.IP, ifindex int, opts *NeighGetOptions) (*NeighEntry, error) {
+	if opts == nil {
+		opts = &NeighGetOptions{}
+	}
+
+	var query NeighEntry
+	query.Addr = dst
+	query.Ifindex = ifindex
+	if opts.Proxy {
+		query.Flags = iproute2.NtfProxy
+	}
+
+	if opts.Resolve {
+		if opts.Proxy {
+			return nil, errors.New("neighbour: proxy neighbour can't be resolved")
+		}
+		e := query
+		e.Flags = iproute2.NtfUse
+		err := c.modifyNeighbour(iproute2.RTM_NEWNEIGH, netlink.Create|netlink.Replace, &e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var msg netlink.Message
+	msg.Header.Type = iproute2.RTM_GETNEIGH
+	msg.Header.Flags = netlink.Request
+	data, err := marshalNeighMsg(&query)
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = data
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		if msg.Header.Type != iproute2.RTM_NEWNEIGH {
+			continue
+		}
+
+		e, ok, err := parseNeighMsg(&msg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e, nil
+		}
+	}
+	return nil, errors.New("neighbour: no neighbour in the response")
 }
@@ -0,0 +1,156 @@
+package ip
+
+import (
+	"errors"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	NETCONFA_IFINDEX                     = 0x1
+	NETCONFA_FORWARDING                  = 0x2
+	NETCONFA_RP_FILTER                   = 0x3
+	NETCONFA_MC_FORWARDING               = 0x4
+	NETCONFA_PROXY_NEIGH                 = 0x5
+	NETCONFA_IGNORE_ROUTES_WITH_LINKDOWN = 0x6
+	NETCONFA_INPUT                       = 0x7
+	NETCONFA_BC_FORWARDING               = 0x8
+
+	// NETCONFA_IFINDEX_ALL and NETCONFA_IFINDEX_DEFAULT are the ifindex
+	// of the `all` and `default` configurations.
+	NETCONFA_IFINDEX_ALL     = -1
+	NETCONFA_IFINDEX_DEFAULT = -2
+)
+
+// A NetconfEntry is the network configuration of a family on a link,
+// which is from the net.<family>.conf.<dev> sysctls. A field is -1 if
+// the family does not have it, like Input that is for MPLS only.
+type NetconfEntry struct {
+	Family                   int
+	Ifindex                  int
+	Forwarding               int
+	RPFilter                 int
+	MCForwarding             int
+	BCForwarding             int
+	ProxyNeigh               int
+	IgnoreRoutesWithLinkdown int
+	Input                    int
+}
+
+func (e *NetconfEntry) init() {
+	e.Forwarding = -1
+	e.RPFilter = -1
+	e.MCForwarding = -1
+	e.BCForwarding = -1
+	e.ProxyNeigh = -1
+	e.IgnoreRoutesWithLinkdown = -1
+	e.Input = -1
+}
+
+// ListNetconfs gets the network configurations of the family on all
+// links, including the `all` and `default` ones.
+func (c *Client) ListNetconfs(family int) ([]*NetconfEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNETCONF
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ncm iproute2.NetconfMsg
+	ncm.Family = uint8(family)
+	msg.Data, _ = ncm.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*NetconfEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNETCONF {
+			continue
+		}
+
+		e, ok, err := parseNetconfMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// GetNetconf gets the network configuration of the family on the link,
+// whose ifindex may be NETCONFA_IFINDEX_ALL or NETCONFA_IFINDEX_DEFAULT.
+func (c *Client) GetNetconf(family, ifindex int) (*NetconfEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNETCONF
+	msg.Header.Flags = netlink.Request
+
+	var ncm iproute2.NetconfMsg
+	ncm.Family = uint8(family)
+	msg.Data, _ = ncm.MarshalBinary()
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Int32(NETCONFA_IFINDEX, int32(ifindex))
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = append(msg.Data, attrs...)
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNETCONF {
+			continue
+		}
+		e, _, err := parseNetconfMsg(&msg)
+		return e, err
+	}
+	return nil, errors.New("netconf: no reply from kernel")
+}
+
+// parseNetconfMsg parses a network configuration from a netlink message.
+func parseNetconfMsg(msg *netlink.Message) (*NetconfEntry, bool, error) {
+	var ncm iproute2.NetconfMsg
+	if err := ncm.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e NetconfEntry
+	e.init()
+	e.Family = int(ncm.Family)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNetconfMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case NETCONFA_IFINDEX:
+			e.Ifindex = int(ad.Int32())
+		case NETCONFA_FORWARDING:
+			e.Forwarding = int(ad.Int32())
+		case NETCONFA_RP_FILTER:
+			e.RPFilter = int(ad.Int32())
+		case NETCONFA_MC_FORWARDING:
+			e.MCForwarding = int(ad.Int32())
+		case NETCONFA_BC_FORWARDING:
+			e.BCForwarding = int(ad.Int32())
+		case NETCONFA_PROXY_NEIGH:
+			e.ProxyNeigh = int(ad.Int32())
+		case NETCONFA_IGNORE_ROUTES_WITH_LINKDOWN:
+			e.IgnoreRoutesWithLinkdown = int(ad.Int32())
+		case NETCONFA_INPUT:
+			e.Input = int(ad.Int32())
+		}
+	}
+	err = ad.Err()
+	return &e, err == nil, err
+}
@@ -0,0 +1,584 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	NHA_FDB        = 0xb
+	NHA_RES_GROUP  = 0xc
+	NHA_RES_BUCKET = 0xd
+
+	NEXTHOP_GRP_TYPE_MPATH = 0x0
+	NEXTHOP_GRP_TYPE_RES   = 0x1
+
+	NHA_RES_GROUP_BUCKETS          = 0x1
+	NHA_RES_GROUP_IDLE_TIMER       = 0x2
+	NHA_RES_GROUP_UNBALANCED_TIMER = 0x3
+	NHA_RES_GROUP_UNBALANCED_TIME  = 0x4
+
+	NHA_RES_BUCKET_INDEX     = 0x1
+	NHA_RES_BUCKET_IDLE_TIME = 0x2
+	NHA_RES_BUCKET_NH_ID     = 0x3
+
+	RTA_NH_ID = 0x1e
+)
+
+// the kernel reports the timers of resilient groups in clock_t, whose
+// USER_HZ is 100.
+const clockTick = 10 * time.Millisecond
+
+// NexthopGroupType is the type of a nexthop group.
+type NexthopGroupType int
+
+// String returns the name of the group type.
+func (typ NexthopGroupType) String() string {
+	switch typ {
+	case NEXTHOP_GRP_TYPE_MPATH:
+		return "mpath"
+	case NEXTHOP_GRP_TYPE_RES:
+		return "resilient"
+	default:
+		return fmt.Sprintf("%d", typ)
+	}
+}
+
+// ParseNexthopGroupType parses a group type from its name, like `mpath`.
+func ParseNexthopGroupType(s string) (NexthopGroupType, error) {
+	switch s {
+	case "mpath":
+		return NEXTHOP_GRP_TYPE_MPATH, nil
+	case "resilient":
+		return NEXTHOP_GRP_TYPE_RES, nil
+	default:
+		return 0, fmt.Errorf("invalid nexthop group type \"%s\"", s)
+	}
+}
+
+// A NexthopGroupMember is a nexthop of a nexthop group.
+// Weight is the weight of struct nexthop_grp plus one, like iproute2
+// shows.
+type NexthopGroupMember struct {
+	ID     uint32
+	Weight int
+}
+
+// NexthopResilient is the settings of a resilient nexthop group.
+// UnbalancedTime is reported by the kernel only.
+type NexthopResilient struct {
+	Buckets         int
+	IdleTimer       time.Duration
+	UnbalancedTimer time.Duration
+	UnbalancedTime  time.Duration
+}
+
+// A NexthopEntry is a nexthop object, which is a single nexthop or a
+// group of nexthops.
+type NexthopEntry struct {
+	ID       uint32
+	Family   int
+	Scope    RouteScope
+	Protocol RouteProtocol
+	Flags    RouteFlags
+
+	Ifindex   int
+	Gateway   net.IP
+	Blackhole bool
+	FDB       bool
+	Encap     RouteEncap
+	Master    int
+
+	Group     []*NexthopGroupMember
+	GroupType NexthopGroupType
+	Resilient *NexthopResilient
+}
+
+// A NexthopBucket is a bucket of a resilient nexthop group.
+type NexthopBucket struct {
+	ID        uint32
+	Index     int
+	IdleTime  time.Duration
+	NexthopID uint32
+	Flags     RouteFlags
+}
+
+// NexthopFilter selects the nexthops dumped by the kernel.
+type NexthopFilter struct {
+	Ifindex int
+	Master  int
+	Groups  bool
+	FDB     bool
+}
+
+// encode encodes the filter to the attributes of a dump request.
+func (f *NexthopFilter) encode(ae *netlink.AttributeEncoder) {
+	if f.Ifindex != 0 {
+		ae.Uint32(unix.NHA_OIF, uint32(f.Ifindex))
+	}
+	if f.Master != 0 {
+		ae.Uint32(unix.NHA_MASTER, uint32(f.Master))
+	}
+	if f.Groups {
+		ae.Flag(unix.NHA_GROUPS, true)
+	}
+	if f.FDB {
+		ae.Flag(NHA_FDB, true)
+	}
+}
+
+// NexthopBucketFilter selects the buckets dumped by the kernel.
+// ID is the id of the resilient group, and NexthopID is the nexthop
+// the buckets are assigned to.
+type NexthopBucketFilter struct {
+	ID        uint32
+	NexthopID uint32
+	Ifindex   int
+	Master    int
+}
+
+// ListNexthops gets the nexthop objects selected by the filter, which
+// may be nil to get all of them.
+func (c *Client) ListNexthops(filter *NexthopFilter) ([]*NexthopEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNEXTHOP
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var nhmsg iproute2.NhMsg
+	msg.Data, _ = nhmsg.MarshalBinary()
+	if filter != nil {
+		ae := netlink.NewAttributeEncoder()
+		filter.encode(ae)
+		attrs, err := ae.Encode()
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = append(msg.Data, attrs...)
+	}
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*NexthopEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNEXTHOP {
+			continue
+		}
+
+		e, ok, err := parseNexthopMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// GetNexthop gets the nexthop object by its id.
+func (c *Client) GetNexthop(id uint32) (*NexthopEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNEXTHOP
+	msg.Header.Flags = netlink.Request
+
+	var nhmsg iproute2.NhMsg
+	msg.Data, _ = nhmsg.MarshalBinary()
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.NHA_ID, id)
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = append(msg.Data, attrs...)
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNEXTHOP {
+			continue
+		}
+		e, _, err := parseNexthopMsg(&msg)
+		return e, err
+	}
+	return nil, errors.New("nexthop: no reply from kernel")
+}
+
+// AddNexthop adds a nexthop object, and fails if the id exists.
+// The kernel allocates the id if it's zero.
+func (c *Client) AddNexthop(e *NexthopEntry) error {
+	return c.modifyNexthop(unix.RTM_NEWNEXTHOP, netlink.Create|netlink.Excl, e)
+}
+
+// ReplaceNexthop replaces a nexthop object, or adds it if it does not
+// exist.
+func (c *Client) ReplaceNexthop(e *NexthopEntry) error {
+	return c.modifyNexthop(unix.RTM_NEWNEXTHOP, netlink.Create|netlink.Replace, e)
+}
+
+// DelNexthop deletes the nexthop object by its id.
+func (c *Client) DelNexthop(id uint32) error {
+	return c.modifyNexthop(unix.RTM_DELNEXTHOP, 0, &NexthopEntry{ID: id})
+}
+
+// FlushNexthops deletes the nexthop objects selected by the filter, and
+// returns the number of the deleted ones.
+// The nexthops which are gone with their groups are not counted.
+func (c *Client) FlushNexthops(filter *NexthopFilter) (int, error) {
+	entries, err := c.ListNexthops(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		err := c.DelNexthop(e.ID)
+		if errors.Is(err, unix.ENOENT) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (c *Client) modifyNexthop(typ netlink.HeaderType, flags netlink.HeaderFlags, e *NexthopEntry) error {
+	var msg netlink.Message
+	msg.Header.Type = typ
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge | flags
+
+	data, err := marshalNexthopMsg(e)
+	if err != nil {
+		return err
+	}
+	msg.Data = data
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+// marshalNexthopMsg marshals a nexthop object to the data of a netlink
+// message.
+// The family is guessed by the gateway if it's not set, and is IPv4 for
+// the other single nexthops, because the kernel accepts no family only
+// for groups and for deleting.
+func marshalNexthopMsg(e *NexthopEntry) ([]byte, error) {
+	family := e.Family
+	if family == syscall.AF_UNSPEC && e.Gateway != nil {
+		family = syscall.AF_INET6
+		if e.Gateway.To4() != nil {
+			family = syscall.AF_INET
+		}
+	}
+	if family == syscall.AF_UNSPEC && len(e.Group) == 0 &&
+		(e.Blackhole || e.Ifindex != 0) {
+		family = syscall.AF_INET
+	}
+
+	var nhmsg iproute2.NhMsg
+	nhmsg.Family = uint8(family)
+	nhmsg.Scope = uint8(e.Scope)
+	nhmsg.Protocol = uint8(e.Protocol)
+	nhmsg.Flags = uint32(e.Flags)
+
+	ae := netlink.NewAttributeEncoder()
+	if e.ID != 0 {
+		ae.Uint32(unix.NHA_ID, e.ID)
+	}
+	if e.Ifindex != 0 {
+		ae.Uint32(unix.NHA_OIF, uint32(e.Ifindex))
+	}
+	if e.Gateway != nil {
+		gw, err := familyAddr(family, e.Gateway)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NHA_GATEWAY, gw)
+	}
+	if e.Blackhole {
+		ae.Flag(unix.NHA_BLACKHOLE, true)
+	}
+	if e.FDB {
+		ae.Flag(NHA_FDB, true)
+	}
+	if e.Encap != nil {
+		encodeRouteEncap(ae, e.Encap, unix.NHA_ENCAP_TYPE, unix.NHA_ENCAP)
+	}
+
+	if len(e.Group) != 0 {
+		ae.Bytes(unix.NHA_GROUP, marshalNexthopGroup(e.Group))
+		ae.Uint16(unix.NHA_GROUP_TYPE, uint16(e.GroupType))
+	}
+	if e.Resilient != nil {
+		ae.Nested(NHA_RES_GROUP, e.Resilient.encode)
+	}
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	data, _ := nhmsg.MarshalBinary()
+	return append(data, attrs...), nil
+}
+
+// marshalNexthopGroup marshals the members to the list of struct
+// nexthop_grp.
+func marshalNexthopGroup(members []*NexthopGroupMember) []byte {
+	data := make([]byte, 0, len(members)*iproute2.SizeofNexthopGrp)
+	for _, m := range members {
+		var grp iproute2.NexthopGrp
+		grp.Id = m.ID
+		if m.Weight > 0 {
+			grp.Weight = uint8(m.Weight - 1)
+		}
+		b, _ := grp.MarshalBinary()
+		data = append(data, b...)
+	}
+	return data
+}
+
+// encode encodes the settings of the resilient group as the nested
+// attributes of NHA_RES_GROUP. A zero field is left to the kernel.
+func (r *NexthopResilient) encode(ae *netlink.AttributeEncoder) error {
+	if r.Buckets != 0 {
+		ae.Uint16(NHA_RES_GROUP_BUCKETS, uint16(r.Buckets))
+	}
+	if r.IdleTimer != 0 {
+		ae.Uint32(NHA_RES_GROUP_IDLE_TIMER, uint32(r.IdleTimer/clockTick))
+	}
+	if r.UnbalancedTimer != 0 {
+		ae.Uint32(NHA_RES_GROUP_UNBALANCED_TIMER, uint32(r.UnbalancedTimer/clockTick))
+	}
+	return nil
+}
+
+// parseNexthopMsg parses a nexthop object from a netlink message.
+func parseNexthopMsg(msg *netlink.Message) (*NexthopEntry, bool, error) {
+	var nhmsg iproute2.NhMsg
+	if err := nhmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e NexthopEntry
+	e.Family = int(nhmsg.Family)
+	e.Scope = RouteScope(nhmsg.Scope)
+	e.Protocol = RouteProtocol(nhmsg.Protocol)
+	e.Flags = RouteFlags(nhmsg.Flags)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNhMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	var encapType int
+	var encap []byte
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NHA_ID:
+			e.ID = ad.Uint32()
+		case unix.NHA_GROUP:
+			e.Group = unmarshalNexthopGroup(ad.Bytes())
+		case unix.NHA_GROUP_TYPE:
+			e.GroupType = NexthopGroupType(ad.Uint16())
+		case unix.NHA_BLACKHOLE:
+			e.Blackhole = true
+		case unix.NHA_OIF:
+			e.Ifindex = int(ad.Uint32())
+		case unix.NHA_GATEWAY:
+			e.Gateway = net.IP(ad.Bytes())
+		case unix.NHA_ENCAP_TYPE:
+			encapType = int(ad.Uint16())
+		case unix.NHA_ENCAP:
+			encap = ad.Bytes()
+		case unix.NHA_MASTER:
+			e.Master = int(ad.Uint32())
+		case NHA_FDB:
+			e.FDB = true
+		case NHA_RES_GROUP:
+			e.Resilient = new(NexthopResilient)
+			ad.Nested(e.Resilient.decode)
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return &e, false, err
+	}
+	if encap != nil {
+		if e.Encap, err = unmarshalRouteEncap(encapType, encap); err != nil {
+			return &e, false, err
+		}
+	}
+	return &e, true, nil
+}
+
+// unmarshalNexthopGroup unmarshals the list of struct nexthop_grp.
+func unmarshalNexthopGroup(data []byte) []*NexthopGroupMember {
+	var members []*NexthopGroupMember
+	for ; len(data) >= iproute2.SizeofNexthopGrp; data = data[iproute2.SizeofNexthopGrp:] {
+		var grp iproute2.NexthopGrp
+		_ = grp.UnmarshalBinary(data)
+		members = append(members, &NexthopGroupMember{
+			ID:     grp.Id,
+			Weight: int(grp.Weight) + 1,
+		})
+	}
+	return members
+}
+
+// decode decodes the nested attributes of NHA_RES_GROUP.
+func (r *NexthopResilient) decode(ad *netlink.AttributeDecoder) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case NHA_RES_GROUP_BUCKETS:
+			r.Buckets = int(ad.Uint16())
+		case NHA_RES_GROUP_IDLE_TIMER:
+			r.IdleTimer = time.Duration(ad.Uint32()) * clockTick
+		case NHA_RES_GROUP_UNBALANCED_TIMER:
+			r.UnbalancedTimer = time.Duration(ad.Uint32()) * clockTick
+		case NHA_RES_GROUP_UNBALANCED_TIME:
+			r.UnbalancedTime = time.Duration(ad.Uint64()) * clockTick
+		}
+	}
+	return nil
+}
+
+// ListNexthopBuckets gets the buckets of the resilient groups selected
+// by the filter, which may be nil to get all of them.
+func (c *Client) ListNexthopBuckets(filter *NexthopBucketFilter) ([]*NexthopBucket, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNEXTHOPBUCKET
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var nhmsg iproute2.NhMsg
+	msg.Data, _ = nhmsg.MarshalBinary()
+	if filter != nil {
+		ae := netlink.NewAttributeEncoder()
+		if filter.ID != 0 {
+			ae.Uint32(unix.NHA_ID, filter.ID)
+		}
+		if filter.Ifindex != 0 {
+			ae.Uint32(unix.NHA_OIF, uint32(filter.Ifindex))
+		}
+		if filter.Master != 0 {
+			ae.Uint32(unix.NHA_MASTER, uint32(filter.Master))
+		}
+		if filter.NexthopID != 0 {
+			ae.Nested(NHA_RES_BUCKET, func(nae *netlink.AttributeEncoder) error {
+				nae.Uint32(NHA_RES_BUCKET_NH_ID, filter.NexthopID)
+				return nil
+			})
+		}
+		attrs, err := ae.Encode()
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = append(msg.Data, attrs...)
+	}
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]*NexthopBucket, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNEXTHOPBUCKET {
+			continue
+		}
+
+		b, ok, err := parseNexthopBucketMsg(&msg)
+		if err != nil {
+			return buckets, err
+		}
+		if ok {
+			buckets = append(buckets, b)
+		}
+	}
+	return buckets, nil
+}
+
+// GetNexthopBucket gets the bucket of the resilient group by its index.
+func (c *Client) GetNexthopBucket(id uint32, index int) (*NexthopBucket, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNEXTHOPBUCKET
+	msg.Header.Flags = netlink.Request
+
+	var nhmsg iproute2.NhMsg
+	msg.Data, _ = nhmsg.MarshalBinary()
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.NHA_ID, id)
+	ae.Nested(NHA_RES_BUCKET, func(nae *netlink.AttributeEncoder) error {
+		nae.Uint16(NHA_RES_BUCKET_INDEX, uint16(index))
+		return nil
+	})
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = append(msg.Data, attrs...)
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNEXTHOPBUCKET {
+			continue
+		}
+		b, _, err := parseNexthopBucketMsg(&msg)
+		return b, err
+	}
+	return nil, errors.New("nexthop: no reply from kernel")
+}
+
+// parseNexthopBucketMsg parses a bucket of a resilient group from a
+// netlink message.
+func parseNexthopBucketMsg(msg *netlink.Message) (*NexthopBucket, bool, error) {
+	var nhmsg iproute2.NhMsg
+	if err := nhmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var b NexthopBucket
+	b.Flags = RouteFlags(nhmsg.Flags)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNhMsg:])
+	if err != nil {
+		return &b, false, err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NHA_ID:
+			b.ID = ad.Uint32()
+		case NHA_RES_BUCKET:
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case NHA_RES_BUCKET_INDEX:
+						b.Index = int(nad.Uint16())
+					case NHA_RES_BUCKET_IDLE_TIME:
+						b.IdleTime = time.Duration(nad.Uint64()) * clockTick
+					case NHA_RES_BUCKET_NH_ID:
+						b.NexthopID = nad.Uint32()
+					}
+				}
+				return nil
+			})
+		}
+	}
+	err = ad.Err()
+	return &b, err == nil, err
+}
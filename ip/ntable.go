@@ -0,0 +1,402 @@
+package ip
+
+import (
+	"time"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	NDTA_NAME        = 0x1
+	NDTA_THRESH1     = 0x2
+	NDTA_THRESH2     = 0x3
+	NDTA_THRESH3     = 0x4
+	NDTA_CONFIG      = 0x5
+	NDTA_PARMS       = 0x6
+	NDTA_STATS       = 0x7
+	NDTA_GC_INTERVAL = 0x8
+
+	NDTPA_IFINDEX                = 0x1
+	NDTPA_REFCNT                 = 0x2
+	NDTPA_REACHABLE_TIME         = 0x3
+	NDTPA_BASE_REACHABLE_TIME    = 0x4
+	NDTPA_RETRANS_TIME           = 0x5
+	NDTPA_GC_STALETIME           = 0x6
+	NDTPA_DELAY_PROBE_TIME       = 0x7
+	NDTPA_QUEUE_LEN              = 0x8
+	NDTPA_APP_PROBES             = 0x9
+	NDTPA_UCAST_PROBES           = 0xa
+	NDTPA_MCAST_PROBES           = 0xb
+	NDTPA_ANYCAST_DELAY          = 0xc
+	NDTPA_PROXY_DELAY            = 0xd
+	NDTPA_PROXY_QLEN             = 0xe
+	NDTPA_LOCKTIME               = 0xf
+	NDTPA_QUEUE_LENBYTES         = 0x10
+	NDTPA_MCAST_REPROBES         = 0x11
+	NDTPA_INTERVAL_PROBE_TIME_MS = 0x13
+)
+
+// A NeighTable is a neighbour table of the kernel, like arp_cache and
+// ndisc_cache. The kernel reports a table with its global settings and
+// default parameters, and a table for each device with the parameters of
+// the device only, whose Parms.Ifindex is not 0.
+//
+// The thresholds and the GC interval are -1 if they're not reported.
+type NeighTable struct {
+	Family     int
+	Name       string
+	Thresh1    int
+	Thresh2    int
+	Thresh3    int
+	GCInterval time.Duration
+	Config     *NeighTableConfig
+	Stats      *NeighTableStats
+	Parms      *NeighTableParms
+}
+
+func (t *NeighTable) init() {
+	t.Thresh1 = -1
+	t.Thresh2 = -1
+	t.Thresh3 = -1
+	t.GCInterval = -1
+}
+
+// NeighTableConfig is the configuration of a neighbour table, LastFlush
+// and LastRand are the time elapsed since then.
+type NeighTableConfig struct {
+	KeyLen        int
+	EntrySize     int
+	Entries       int
+	LastFlush     time.Duration
+	LastRand      time.Duration
+	HashRnd       uint32
+	HashMask      uint32
+	HashChainGC   uint32
+	ProxyQueueLen int
+}
+
+// NeighTableStats is the statistics of a neighbour table.
+type NeighTableStats struct {
+	Allocs         uint64
+	Destroys       uint64
+	HashGrows      uint64
+	ResFailed      uint64
+	Lookups        uint64
+	Hits           uint64
+	RcvProbesMcast uint64
+	RcvProbesUcast uint64
+	PeriodicGCRuns uint64
+	ForcedGCRuns   uint64
+	TableFulls     uint64
+}
+
+// NeighTableParms is the parameters of a neighbour table, which are the
+// default ones of the table if Ifindex is 0. A field is -1 if it's not
+// reported, like IntervalProbeTime of the older kernels.
+type NeighTableParms struct {
+	Ifindex           int
+	RefCount          int
+	ReachableTime     time.Duration
+	BaseReachableTime time.Duration
+	RetransTime       time.Duration
+	GCStaleTime       time.Duration
+	DelayProbeTime    time.Duration
+	QueueLen          int
+	QueueLenBytes     int
+	AppProbes         int
+	UcastProbes       int
+	McastProbes       int
+	McastReprobes     int
+	AnycastDelay      time.Duration
+	ProxyDelay        time.Duration
+	ProxyQueueLen     int
+	LockTime          time.Duration
+	IntervalProbeTime time.Duration
+}
+
+func (p *NeighTableParms) init() {
+	p.RefCount = -1
+	p.ReachableTime = -1
+	p.BaseReachableTime = -1
+	p.RetransTime = -1
+	p.GCStaleTime = -1
+	p.DelayProbeTime = -1
+	p.QueueLen = -1
+	p.QueueLenBytes = -1
+	p.AppProbes = -1
+	p.UcastProbes = -1
+	p.McastProbes = -1
+	p.McastReprobes = -1
+	p.AnycastDelay = -1
+	p.ProxyDelay = -1
+	p.ProxyQueueLen = -1
+	p.LockTime = -1
+	p.IntervalProbeTime = -1
+}
+
+// NeighTableChange is the settings of a neighbour table to change, and
+// the nil fields are not changed. The table is selected by Name, and
+// Family if it's not 0. The parameters are the default ones of the table,
+// or the ones of the device if Ifindex is not 0, while the thresholds and
+// the GC interval are global.
+type NeighTableChange struct {
+	Family  int
+	Name    string
+	Ifindex int
+
+	Thresh1    *uint32
+	Thresh2    *uint32
+	Thresh3    *uint32
+	GCInterval *time.Duration
+
+	BaseReachableTime *time.Duration
+	RetransTime       *time.Duration
+	GCStaleTime       *time.Duration
+	DelayProbeTime    *time.Duration
+	QueueLen          *uint32
+	QueueLenBytes     *uint32
+	AppProbes         *uint32
+	UcastProbes       *uint32
+	McastProbes       *uint32
+	McastReprobes     *uint32
+	AnycastDelay      *time.Duration
+	ProxyDelay        *time.Duration
+	ProxyQueueLen     *uint32
+	LockTime          *time.Duration
+	IntervalProbeTime *time.Duration
+}
+
+// ListNeighTables gets the neighbour tables of the family, which may be
+// AF_UNSPEC for all the families.
+func (c *Client) ListNeighTables(family int) ([]*NeighTable, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETNEIGHTBL
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ndtmsg iproute2.NdtMsg
+	ndtmsg.Family = uint8(family)
+	msg.Data, _ = ndtmsg.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]*NeighTable, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWNEIGHTBL {
+			continue
+		}
+
+		t, err := parseNeighTableMsg(&msg)
+		if err != nil {
+			return tables, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// ChangeNeighTable changes the settings of a neighbour table.
+// The kernel only allows the global settings and the default parameters
+// to be changed in the initial network namespace, and fails with ENOENT
+// in the others.
+func (c *Client) ChangeNeighTable(ch *NeighTableChange) error {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_SETNEIGHTBL
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge
+
+	var ndtmsg iproute2.NdtMsg
+	ndtmsg.Family = uint8(ch.Family)
+	msg.Data, _ = ndtmsg.MarshalBinary()
+
+	attrs, err := marshalNeighTableChange(ch)
+	if err != nil {
+		return err
+	}
+	msg.Data = append(msg.Data, attrs...)
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+func marshalNeighTableChange(ch *NeighTableChange) ([]byte, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(NDTA_NAME, ch.Name)
+	if ch.Thresh1 != nil {
+		ae.Uint32(NDTA_THRESH1, *ch.Thresh1)
+	}
+	if ch.Thresh2 != nil {
+		ae.Uint32(NDTA_THRESH2, *ch.Thresh2)
+	}
+	if ch.Thresh3 != nil {
+		ae.Uint32(NDTA_THRESH3, *ch.Thresh3)
+	}
+	if ch.GCInterval != nil {
+		ae.Uint64(NDTA_GC_INTERVAL, uint64(ch.GCInterval.Milliseconds()))
+	}
+
+	msecs := []struct {
+		typ uint16
+		val *time.Duration
+	}{
+		{NDTPA_BASE_REACHABLE_TIME, ch.BaseReachableTime},
+		{NDTPA_RETRANS_TIME, ch.RetransTime},
+		{NDTPA_GC_STALETIME, ch.GCStaleTime},
+		{NDTPA_DELAY_PROBE_TIME, ch.DelayProbeTime},
+		{NDTPA_ANYCAST_DELAY, ch.AnycastDelay},
+		{NDTPA_PROXY_DELAY, ch.ProxyDelay},
+		{NDTPA_LOCKTIME, ch.LockTime},
+		{NDTPA_INTERVAL_PROBE_TIME_MS, ch.IntervalProbeTime},
+	}
+	counts := []struct {
+		typ uint16
+		val *uint32
+	}{
+		{NDTPA_QUEUE_LEN, ch.QueueLen},
+		{NDTPA_QUEUE_LENBYTES, ch.QueueLenBytes},
+		{NDTPA_APP_PROBES, ch.AppProbes},
+		{NDTPA_UCAST_PROBES, ch.UcastProbes},
+		{NDTPA_MCAST_PROBES, ch.McastProbes},
+		{NDTPA_MCAST_REPROBES, ch.McastReprobes},
+		{NDTPA_PROXY_QLEN, ch.ProxyQueueLen},
+	}
+
+	hasParms := ch.Ifindex != 0
+	for _, m := range msecs {
+		hasParms = hasParms || m.val != nil
+	}
+	for _, c := range counts {
+		hasParms = hasParms || c.val != nil
+	}
+	if hasParms {
+		ae.Nested(NDTA_PARMS, func(nae *netlink.AttributeEncoder) error {
+			if ch.Ifindex != 0 {
+				nae.Uint32(NDTPA_IFINDEX, uint32(ch.Ifindex))
+			}
+			for _, m := range msecs {
+				if m.val != nil {
+					nae.Uint64(m.typ, uint64(m.val.Milliseconds()))
+				}
+			}
+			for _, c := range counts {
+				if c.val != nil {
+					nae.Uint32(c.typ, *c.val)
+				}
+			}
+			return nil
+		})
+	}
+	return ae.Encode()
+}
+
+// parseNeighTableMsg parses a neighbour table from a netlink message.
+func parseNeighTableMsg(msg *netlink.Message) (*NeighTable, error) {
+	var ndtmsg iproute2.NdtMsg
+	if err := ndtmsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, err
+	}
+
+	var t NeighTable
+	t.init()
+	t.Family = int(ndtmsg.Family)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofNdtMsg:])
+	if err != nil {
+		return nil, err
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case NDTA_NAME:
+			t.Name = ad.String()
+		case NDTA_THRESH1:
+			t.Thresh1 = int(ad.Uint32())
+		case NDTA_THRESH2:
+			t.Thresh2 = int(ad.Uint32())
+		case NDTA_THRESH3:
+			t.Thresh3 = int(ad.Uint32())
+		case NDTA_GC_INTERVAL:
+			t.GCInterval = time.Duration(ad.Uint64()) * time.Millisecond
+		case NDTA_CONFIG:
+			var cfg iproute2.NdtConfig
+			if err := cfg.UnmarshalBinary(ad.Bytes()); err != nil {
+				return nil, err
+			}
+			t.Config = &NeighTableConfig{
+				KeyLen:        int(cfg.KeyLen),
+				EntrySize:     int(cfg.EntrySize),
+				Entries:       int(cfg.Entries),
+				LastFlush:     time.Duration(cfg.LastFlush) * time.Millisecond,
+				LastRand:      time.Duration(cfg.LastRand) * time.Millisecond,
+				HashRnd:       cfg.HashRnd,
+				HashMask:      cfg.HashMask,
+				HashChainGC:   cfg.HashChainGC,
+				ProxyQueueLen: int(cfg.ProxyQlen),
+			}
+		case NDTA_STATS:
+			var st iproute2.NdtStats
+			if err := st.UnmarshalBinary(ad.Bytes()); err != nil {
+				return nil, err
+			}
+			stats := NeighTableStats(st)
+			t.Stats = &stats
+		case NDTA_PARMS:
+			t.Parms = new(NeighTableParms)
+			t.Parms.init()
+			ad.Nested(t.Parms.decode)
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (p *NeighTableParms) decode(ad *netlink.AttributeDecoder) error {
+	msecs := func() time.Duration {
+		return time.Duration(ad.Uint64()) * time.Millisecond
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case NDTPA_IFINDEX:
+			p.Ifindex = int(ad.Uint32())
+		case NDTPA_REFCNT:
+			p.RefCount = int(ad.Uint32())
+		case NDTPA_REACHABLE_TIME:
+			p.ReachableTime = msecs()
+		case NDTPA_BASE_REACHABLE_TIME:
+			p.BaseReachableTime = msecs()
+		case NDTPA_RETRANS_TIME:
+			p.RetransTime = msecs()
+		case NDTPA_GC_STALETIME:
+			p.GCStaleTime = msecs()
+		case NDTPA_DELAY_PROBE_TIME:
+			p.DelayProbeTime = msecs()
+		case NDTPA_QUEUE_LEN:
+			p.QueueLen = int(ad.Uint32())
+		case NDTPA_QUEUE_LENBYTES:
+			p.QueueLenBytes = int(ad.Uint32())
+		case NDTPA_APP_PROBES:
+			p.AppProbes = int(ad.Uint32())
+		case NDTPA_UCAST_PROBES:
+			p.UcastProbes = int(ad.Uint32())
+		case NDTPA_MCAST_PROBES:
+			p.McastProbes = int(ad.Uint32())
+		case NDTPA_MCAST_REPROBES:
+			p.McastReprobes = int(ad.Uint32())
+		case NDTPA_ANYCAST_DELAY:
+			p.AnycastDelay = msecs()
+		case NDTPA_PROXY_DELAY:
+			p.ProxyDelay = msecs()
+		case NDTPA_PROXY_QLEN:
+			p.ProxyQueueLen = int(ad.Uint32())
+		case NDTPA_LOCKTIME:
+			p.LockTime = msecs()
+		case NDTPA_INTERVAL_PROBE_TIME_MS:
+			p.IntervalProbeTime = msecs()
+		}
+	}
+	return nil
+}
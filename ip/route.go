@@ -1,13 +1,14 @@
 package ip
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Asphaltt/go-iproute2"
-	"github.com/Asphaltt/go-iproute2/internal/etc"
 	"github.com/mdlayher/netlink"
 	"golang.org/x/sys/unix"
 )
@@ -45,68 +46,95 @@ func (typ RouteType) String() string {
 	}
 }
 
+// ParseRouteType parses a route type from its name, like `unicast`.
+func ParseRouteType(s string) (RouteType, error) {
+	for typ := RouteType(unix.RTN_UNSPEC); typ <= unix.RTN_MAX; typ++ {
+		if typ.String() == s {
+			return typ, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid route type \"%s\"", s)
+}
+
 type RouteTable int
 
 func (t RouteTable) String() string {
-	names, _ := etc.ReadRouteTables()
-	names[unix.RT_TABLE_DEFAULT] = "default"
-	names[unix.RT_TABLE_MAIN] = "main"
-	names[unix.RT_TABLE_LOCAL] = "local"
+	return RouteTableNames.Format(int(t))
+}
 
-	if name, ok := names[int(t)]; ok {
-		return name
+// ParseRouteTable parses a route table from its name or number.
+func ParseRouteTable(s string) (RouteTable, error) {
+	id, ok := RouteTableNames.ID(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid table \"%s\"", s)
 	}
-	return fmt.Sprintf("%d", t)
+	return RouteTable(id), nil
 }
 
 type RouteProtocol int
 
 func (p RouteProtocol) String() string {
-	protocols, _ := etc.ReadRouteProtos()
-	protocols[unix.RTPROT_UNSPEC] = "unspec"
-	protocols[unix.RTPROT_REDIRECT] = "redirect"
-	protocols[unix.RTPROT_KERNEL] = "kernel"
-	protocols[unix.RTPROT_BOOT] = "boot"
-	protocols[unix.RTPROT_STATIC] = "static"
-	protocols[unix.RTPROT_GATED] = "gated"
-	protocols[unix.RTPROT_RA] = "ra"
-	protocols[unix.RTPROT_MRT] = "mrt"
-	protocols[unix.RTPROT_ZEBRA] = "zebra"
-	protocols[unix.RTPROT_BIRD] = "bird"
-	protocols[unix.RTPROT_BABEL] = "babel"
-	protocols[unix.RTPROT_DNROUTED] = "dnrouted"
-	protocols[unix.RTPROT_XORP] = "xorp"
-	protocols[unix.RTPROT_NTK] = "ntk"
-	protocols[unix.RTPROT_DHCP] = "dhcp"
-	protocols[unix.RTPROT_KEEPALIVED] = "keepalived"
-	protocols[unix.RTPROT_BGP] = "bgp"
-	protocols[unix.RTPROT_ISIS] = "isis"
-	protocols[unix.RTPROT_OSPF] = "ospf"
-	protocols[unix.RTPROT_RIP] = "rip"
-	protocols[unix.RTPROT_EIGRP] = "eigrp"
-
-	if proto, ok := protocols[int(p)]; ok {
-		return proto
-	}
-	return fmt.Sprintf("%d", p)
+	return RouteProtocolNames.Format(int(p))
+}
+
+// ParseRouteProtocol parses a route protocol from its name or number.
+func ParseRouteProtocol(s string) (RouteProtocol, error) {
+	id, ok := RouteProtocolNames.ID(s)
+	if !ok || id > 0xff {
+		return 0, fmt.Errorf("invalid protocol \"%s\"", s)
+	}
+	return RouteProtocol(id), nil
 }
 
 type RouteScope int
 
 func (s RouteScope) String() string {
-	scopes, _ := etc.ReadRouteScopes()
-	scopes[unix.RT_SCOPE_UNIVERSE] = "global"
-	scopes[unix.RT_SCOPE_NOWHERE] = "nowhere"
-	scopes[unix.RT_SCOPE_HOST] = "host"
-	scopes[unix.RT_SCOPE_LINK] = "link"
-	scopes[unix.RT_SCOPE_SITE] = "site"
+	return RouteScopeNames.Format(int(s))
+}
 
-	if scope, ok := scopes[int(s)]; ok {
-		return scope
+// ParseRouteScope parses a route scope from its name or number.
+func ParseRouteScope(s string) (RouteScope, error) {
+	id, ok := RouteScopeNames.ID(s)
+	if !ok || id > 0xff {
+		return 0, fmt.Errorf("invalid scope \"%s\"", s)
+	}
+	return RouteScope(id), nil
+}
+
+// DSField is the DS field of IP header, which is the TOS of the routes
+// and the rules.
+type DSField int
+
+// String returns the name of the DS field from */etc/iproute2/rt_dsfield*,
+// or the hexadecimal value like `0x10`.
+func (f DSField) String() string {
+	if name, ok := DSFieldNames.Name(int(f)); ok {
+		return name
 	}
-	return fmt.Sprintf("%d", s)
+	return fmt.Sprintf("0x%02x", int(f))
 }
 
+// ParseDSField parses a DS field from its name or number.
+func ParseDSField(s string) (DSField, error) {
+	id, ok := DSFieldNames.ID(s)
+	if !ok || id > 0xff {
+		return 0, fmt.Errorf("invalid dsfield \"%s\"", s)
+	}
+	return DSField(id), nil
+}
+
+// RealmsFrom gets the source realm of the realms like RTA_FLOW, which is
+// the upper 16 bits.
+func RealmsFrom(realms uint32) int { return int(realms >> 16) }
+
+// RealmsTo gets the destination realm of the realms like RTA_FLOW, which
+// is the lower 16 bits.
+func RealmsTo(realms uint32) int { return int(realms & 0xffff) }
+
+// MakeRealms makes the realms like RTA_FLOW from the source and the
+// destination realms.
+func MakeRealms(from, to int) uint32 { return uint32(from)<<16 | uint32(to)&0xffff }
+
 type RouteFlags int
 
 const (
@@ -173,6 +201,19 @@ func (p RoutePref) String() string {
 	return prefs[int(p)]
 }
 
+// ParseRoutePref parses an IPv6 router preference from its name.
+func ParseRoutePref(s string) (RoutePref, error) {
+	switch s {
+	case "low":
+		return ICMPV6_ROUTER_PREF_LOW, nil
+	case "medium":
+		return ICMPV6_ROUTER_PREF_MEDIUM, nil
+	case "high":
+		return ICMPV6_ROUTER_PREF_HIGH, nil
+	}
+	return 0, fmt.Errorf("invalid pref \"%s\"", s)
+}
+
 type RouteEntry struct {
 	Family   int
 	DstLen   int
@@ -192,14 +233,58 @@ type RouteEntry struct {
 	Table      RouteTable
 	Priority   int
 	PrefSrc    net.IP
-	Metric     int
+	Metrics    *RouteMetrics
 	Pref       RoutePref
+	Expires    int
+	Via        *RouteVia
+	MultiPath  []*RouteNextHop
+	Label      int
+	NewDst     []uint32
+	TTLProp    MPLSTTLPropagate
+	Mark       uint32
+	UID        int
+	NexthopID  uint32
+	Realms     uint32
+	Encap      RouteEncap
+	CacheInfo  *RouteCacheInfo
+}
+
+// RouteCacheInfo is the cache information of a route, which is mostly
+// for the cloned routes, like the PMTU and redirect exceptions.
+type RouteCacheInfo struct {
+	Users   int
+	LastUse time.Duration
+	Expires time.Duration
+	Error   int
+	Used    int
+	IPID    uint32
+	TS      uint32
+	TSAge   uint32
+}
+
+func unmarshalRouteCacheInfo(data []byte) (*RouteCacheInfo, error) {
+	var ci iproute2.RtaCacheinfo
+	if err := ci.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &RouteCacheInfo{
+		Users:   int(ci.Clntref),
+		LastUse: time.Duration(ci.Lastuse) * clockTick,
+		Expires: time.Duration(ci.Expires) * clockTick,
+		Error:   int(ci.Error),
+		Used:    int(ci.Used),
+		IPID:    ci.Id,
+		TS:      ci.Ts,
+		TSAge:   ci.Tsage,
+	}, nil
 }
 
 func (e *RouteEntry) init() {
 	e.Table = -1
 	e.Priority = -1
 	e.Pref = -1
+	e.UID = -1
+	e.Label = -1
 }
 
 func (c *Client) ListRoutes() ([]*RouteEntry, error) {
@@ -214,6 +299,12 @@ func (c *Client) ListRoutesV6() ([]*RouteEntry, error) {
 	return c.listRoutes(syscall.AF_INET6)
 }
 
+// ListRoutesMPLS gets the routes of the MPLS label table, whose Label
+// is the incoming label.
+func (c *Client) ListRoutesMPLS() ([]*RouteEntry, error) {
+	return c.listRoutes(unix.AF_MPLS)
+}
+
 func (c *Client) listRoutes(family uint8) ([]*RouteEntry, error) {
 	var msg netlink.Message
 	msg.Header.Type = unix.RTM_GETROUTE
@@ -238,7 +329,9 @@ func (c *Client) listRoutes(family uint8) ([]*RouteEntry, error) {
 		if err != nil {
 			return entries, err
 		}
-		if ok {
+		// the kernel dumps all families if the family is not registered,
+		// like AF_MPLS without the mpls_router module.
+		if ok && (family == unix.AF_UNSPEC || e.Family == int(family)) {
 			entries = append(entries, e)
 		}
 	}
@@ -269,10 +362,25 @@ func parseRouteMsg(msg *netlink.Message) (*RouteEntry, bool, error) {
 		return &e, false, err
 	}
 
+	var encapType int
+	var encap []byte
 	for ad.Next() {
 		switch ad.Type() {
 		case unix.RTA_DST:
+			if e.Family == unix.AF_MPLS {
+				if labels := unmarshalMPLSLabels(ad.Bytes()); len(labels) != 0 {
+					e.Label = int(labels[0])
+				}
+				break
+			}
 			e.Daddr = net.IP(ad.Bytes())
+		case unix.RTA_NEWDST:
+			e.NewDst = unmarshalMPLSLabels(ad.Bytes())
+		case unix.RTA_TTL_PROPAGATE:
+			e.TTLProp = MPLS_TTL_PROP_DISABLED
+			if ad.Uint8() != 0 {
+				e.TTLProp = MPLS_TTL_PROP_ENABLED
+			}
 		case unix.RTA_SRC:
 			e.Saddr = net.IP(ad.Bytes())
 		case unix.RTA_IIF:
@@ -286,13 +394,269 @@ func parseRouteMsg(msg *netlink.Message) (*RouteEntry, bool, error) {
 		case unix.RTA_PREFSRC:
 			e.PrefSrc = net.IP(ad.Bytes())
 		case unix.RTA_METRICS:
-			e.Metric = int(ad.Uint32())
+			e.Metrics, err = unmarshalRouteMetrics(ad.Bytes())
+			if err != nil {
+				return &e, false, err
+			}
 		case unix.RTA_TABLE:
 			e.Table = RouteTable(ad.Uint32())
 		case unix.RTA_PREF:
 			e.Pref = RoutePref(ad.Uint8())
+		case unix.RTA_EXPIRES:
+			e.Expires = int(ad.Uint32())
+		case unix.RTA_CACHEINFO:
+			e.CacheInfo, err = unmarshalRouteCacheInfo(ad.Bytes())
+			if err != nil {
+				return &e, false, err
+			}
+		case unix.RTA_VIA:
+			e.Via, err = unmarshalRouteVia(ad.Bytes())
+			if err != nil {
+				return &e, false, err
+			}
+		case unix.RTA_MULTIPATH:
+			e.MultiPath, err = unmarshalMultipath(ad.Bytes())
+			if err != nil {
+				return &e, false, err
+			}
+		case unix.RTA_MARK:
+			e.Mark = ad.Uint32()
+		case unix.RTA_FLOW:
+			e.Realms = ad.Uint32()
+		case unix.RTA_UID:
+			e.UID = int(ad.Uint32())
+		case RTA_NH_ID:
+			e.NexthopID = ad.Uint32()
+		case unix.RTA_ENCAP_TYPE:
+			encapType = int(ad.Uint16())
+		case unix.RTA_ENCAP:
+			encap = ad.Bytes()
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return &e, false, err
+	}
+	if encap != nil {
+		if e.Encap, err = unmarshalRouteEncap(encapType, encap); err != nil {
+			return &e, false, err
+		}
+	}
+	return &e, true, nil
+}
+
+// AddRoute adds a route to kernel, and fails if the route exists.
+func (c *Client) AddRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_NEWROUTE, netlink.Create|netlink.Excl, e)
+}
+
+// DelRoute deletes a route from kernel. The route of any scope is deleted
+// if Scope is not set, which is the zero value RT_SCOPE_UNIVERSE, like
+// iproute2.
+func (c *Client) DelRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_DELROUTE, 0, e)
+}
+
+// ReplaceRoute replaces a route, or adds it if the route does not exist.
+func (c *Client) ReplaceRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_NEWROUTE, netlink.Create|netlink.Replace, e)
+}
+
+// ChangeRoute changes an existing route.
+func (c *Client) ChangeRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_NEWROUTE, netlink.Replace, e)
+}
+
+// AppendRoute adds a route to the end of the routes with the same prefix.
+func (c *Client) AppendRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_NEWROUTE, netlink.Create|netlink.Append, e)
+}
+
+// PrependRoute adds a route to the head of the routes with the same prefix.
+func (c *Client) PrependRoute(e *RouteEntry) error {
+	return c.modifyRoute(unix.RTM_NEWROUTE, netlink.Create, e)
+}
+
+func (c *Client) modifyRoute(typ netlink.HeaderType, flags netlink.HeaderFlags, e *RouteEntry) error {
+	var msg netlink.Message
+	msg.Header.Type = typ
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge | flags
+
+	data, err := marshalRouteMsg(e, typ == unix.RTM_DELROUTE)
+	if err != nil {
+		return err
+	}
+	msg.Data = data
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+// marshalRouteMsg marshals a route information to the data of a netlink
+// message.
+// The table is main if neither TableID nor Table is set, and the type is
+// unicast if it's not set for adding, and the scope is nowhere if it's
+// not set for deleting, which matches any scope. The MPLS route is keyed
+// by Label instead of Daddr.
+func marshalRouteMsg(e *RouteEntry, del bool) ([]byte, error) {
+	family := e.Family
+	if family == syscall.AF_UNSPEC {
+		family = routeFamily(e)
+	}
+
+	var rtmsg iproute2.RtMsg
+	rtmsg.Family = uint8(family)
+	rtmsg.Dst_len = uint8(e.DstLen)
+	rtmsg.Src_len = uint8(e.SrcLen)
+	rtmsg.Tos = uint8(e.Tos)
+	rtmsg.Protocol = uint8(e.Protocol)
+	rtmsg.Scope = uint8(e.Scope)
+	rtmsg.Type = uint8(e.Type)
+	rtmsg.Flags = uint32(e.Flags)
+	if !del && e.Type == unix.RTN_UNSPEC {
+		rtmsg.Type = unix.RTN_UNICAST
+	}
+	if del && e.Scope == unix.RT_SCOPE_UNIVERSE && family != unix.AF_MPLS {
+		rtmsg.Scope = unix.RT_SCOPE_NOWHERE
+	}
+
+	table := e.Table
+	if table <= 0 {
+		table = e.TableID
+	}
+	if table == unix.RT_TABLE_UNSPEC {
+		table = unix.RT_TABLE_MAIN
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	if table < 256 {
+		rtmsg.Table = uint8(table)
+	} else {
+		rtmsg.Table = unix.RT_TABLE_UNSPEC
+		ae.Uint32(unix.RTA_TABLE, uint32(table))
+	}
+
+	if family == unix.AF_MPLS {
+		if e.Label < 0 {
+			return nil, errors.New("route: the label of the MPLS route is required")
+		}
+		rtmsg.Dst_len = 20
+		ae.Bytes(unix.RTA_DST, marshalMPLSLabels([]uint32{uint32(e.Label)}))
+	}
+	if len(e.NewDst) != 0 {
+		ae.Bytes(unix.RTA_NEWDST, marshalMPLSLabels(e.NewDst))
+	}
+	if e.TTLProp != MPLS_TTL_PROP_DEFAULT {
+		var prop uint8
+		if e.TTLProp == MPLS_TTL_PROP_ENABLED {
+			prop = 1
+		}
+		ae.Uint8(unix.RTA_TTL_PROPAGATE, prop)
+	}
+
+	addrs := []struct {
+		typ  uint16
+		addr net.IP
+	}{
+		{unix.RTA_DST, e.Daddr},
+		{unix.RTA_SRC, e.Saddr},
+		{unix.RTA_GATEWAY, e.Gateway},
+		{unix.RTA_PREFSRC, e.PrefSrc},
+	}
+	for _, a := range addrs {
+		if a.addr == nil {
+			continue
+		}
+		addr, err := familyAddr(family, a.addr)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(a.typ, addr)
+	}
+
+	if e.Via != nil {
+		ae.Bytes(unix.RTA_VIA, e.Via.marshalBinary())
+	}
+	if len(e.MultiPath) != 0 {
+		mp, err := marshalMultipath(family, e.MultiPath)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.RTA_MULTIPATH, mp)
+	}
+
+	if e.Metrics != nil {
+		ae.Nested(unix.RTA_METRICS, e.Metrics.encode)
+	}
+	if e.Encap != nil {
+		encodeRouteEncap(ae, e.Encap, unix.RTA_ENCAP_TYPE, unix.RTA_ENCAP)
+	}
+
+	if e.NexthopID != 0 {
+		ae.Uint32(RTA_NH_ID, e.NexthopID)
+	}
+	if e.Realms != 0 {
+		ae.Uint32(unix.RTA_FLOW, e.Realms)
+	}
+	if e.InIfindex != 0 {
+		ae.Uint32(unix.RTA_IIF, uint32(e.InIfindex))
+	}
+	if e.OutIfindex != 0 {
+		ae.Uint32(unix.RTA_OIF, uint32(e.OutIfindex))
+	}
+	if e.Priority > 0 {
+		ae.Uint32(unix.RTA_PRIORITY, uint32(e.Priority))
+	}
+	if family == syscall.AF_INET6 {
+		if e.Pref > 0 {
+			ae.Uint8(unix.RTA_PREF, uint8(e.Pref))
+		}
+		if e.Expires > 0 {
+			ae.Uint32(unix.RTA_EXPIRES, uint32(e.Expires))
+		}
+	}
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	data, _ := rtmsg.MarshalBinary()
+	return append(data, attrs...), nil
+}
+
+// routeFamily guesses the family of the route by its label or addresses,
+// a positive label makes an MPLS route, as the label 0 is reserved and
+// taken as unset, and the kernel rejects the other reserved labels.
+func routeFamily(e *RouteEntry) int {
+	if e.Label > 0 {
+		return unix.AF_MPLS
+	}
+	addrs := []net.IP{e.Daddr, e.Gateway, e.Saddr, e.PrefSrc}
+	for _, nh := range e.MultiPath {
+		addrs = append(addrs, nh.Gateway)
+	}
+	for _, addr := range addrs {
+		if addr == nil {
+			continue
+		}
+		if addr.To4() != nil {
+			return syscall.AF_INET
+		}
+		return syscall.AF_INET6
+	}
+	return syscall.AF_INET
+}
+
+// familyAddr converts the address to the length of the family.
+func familyAddr(family int, addr net.IP) (net.IP, error) {
+	switch family {
+	case syscall.AF_INET:
+		if ip := addr.To4(); ip != nil {
+			return ip, nil
+		}
+	case syscall.AF_INET6:
+		if len(addr) == net.IPv6len {
+			return addr, nil
 		}
 	}
-	err = ad.Err()
-	return &e, err == nil, err
+	return nil, errors.New("route: address family mismatch")
 }
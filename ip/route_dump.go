@@ -0,0 +1,198 @@
+package ip
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+// routeDumpMagic is the magic number at the beginning of the route dump
+// of iproute2, which is in host byte order.
+const routeDumpMagic = 0x45311224
+
+// ErrInvalidRouteDump is returned when the data is not a route dump of
+// `ip route save`.
+var ErrInvalidRouteDump = errors.New("route: invalid route dump")
+
+// SaveRoutes writes the routes selected by the filter to w in the binary
+// format of `ip route save`, and returns the number of the saved routes.
+// The filter may be nil to save the routes of all families in the main
+// table.
+//
+// The routes are saved as the netlink messages dumped from the kernel, so
+// all of their attributes, like the table, the nexthops and the metrics,
+// are kept as they are.
+func (c *Client) SaveRoutes(w io.Writer, filter *RouteFilter) (int, error) {
+	msgs, _, err := c.dumpRoutes(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	magic := make([]byte, 4)
+	nlenc.PutUint32(magic, routeDumpMagic)
+	if _, err := w.Write(magic); err != nil {
+		return 0, err
+	}
+
+	for i, msg := range msgs {
+		data, err := msg.MarshalBinary()
+		if err != nil {
+			return i, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return i, err
+		}
+	}
+	return len(msgs), nil
+}
+
+// readRouteDump reads the route messages from the route dump.
+func readRouteDump(r io.Reader) ([]netlink.Message, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || nlenc.Uint32(data[:4]) != routeDumpMagic {
+		return nil, ErrInvalidRouteDump
+	}
+	data = data[4:]
+
+	var msgs []netlink.Message
+	for len(data) != 0 {
+		if len(data) < unix.NLMSG_HDRLEN {
+			return nil, ErrInvalidRouteDump
+		}
+		n := int(nlenc.Uint32(data[:4]))
+		if n < unix.NLMSG_HDRLEN || n > len(data) {
+			return nil, ErrInvalidRouteDump
+		}
+
+		// the header is decoded here as the length of the message may be
+		// unaligned.
+		msg := netlink.Message{
+			Header: netlink.Header{
+				Length:   uint32(n),
+				Type:     netlink.HeaderType(nlenc.Uint16(data[4:6])),
+				Flags:    netlink.HeaderFlags(nlenc.Uint16(data[6:8])),
+				Sequence: nlenc.Uint32(data[8:12]),
+				PID:      nlenc.Uint32(data[12:16]),
+			},
+			Data: data[unix.NLMSG_HDRLEN:n],
+		}
+		if msg.Header.Type == unix.RTM_NEWROUTE {
+			msgs = append(msgs, msg)
+		}
+
+		if n = nlmsgAlign(n); n > len(data) {
+			n = len(data)
+		}
+		data = data[n:]
+	}
+	return msgs, nil
+}
+
+// ParseRouteDump parses the routes from the data written by `ip route
+// save` or SaveRoutes, like `ip route showdump`.
+func ParseRouteDump(r io.Reader) ([]*RouteEntry, error) {
+	msgs, err := readRouteDump(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*RouteEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		e, ok, err := parseRouteMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// RestoreRoutes adds the routes read from the data written by `ip route
+// save` or SaveRoutes, and returns the number of the restored routes. The
+// existing routes are skipped.
+//
+// The routes are restored in the same order as iproute2, the routes for
+// the local addresses first, then the ones for the local networks, and
+// the ones via gateways last, so the gateways are reachable when their
+// routes are added.
+func (c *Client) RestoreRoutes(r io.Reader) (int, error) {
+	msgs, err := readRouteDump(r)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for prio := 0; prio < 3; prio++ {
+		for _, msg := range msgs {
+			if routeRestorePriority(&msg) != prio {
+				continue
+			}
+
+			req := netlink.Message{
+				Header: netlink.Header{
+					Type:  unix.RTM_NEWROUTE,
+					Flags: netlink.Request | netlink.Create | netlink.Excl | netlink.Acknowledge,
+				},
+				Data: msg.Data,
+			}
+			_, err := c.conn.Execute(req)
+			switch {
+			case err == nil:
+				n++
+			case errors.Is(err, unix.EEXIST):
+			default:
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// routeRestorePriority gets the order of the route to be restored, which
+// is 0 for the routes for the local addresses, 1 for the ones for the
+// local networks, and 2 for the ones via gateways. Unlike iproute2, the
+// multipath routes are taken as the ones via gateways, as their nexthops
+// mostly are.
+func routeRestorePriority(msg *netlink.Message) int {
+	if len(msg.Data) < unix.SizeofRtMsg {
+		return 0
+	}
+
+	var hasGateway, hasPrefSrc bool
+	ad, err := netlink.NewAttributeDecoder(msg.Data[unix.SizeofRtMsg:])
+	if err != nil {
+		return 0
+	}
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.RTA_GATEWAY, unix.RTA_VIA, unix.RTA_MULTIPATH:
+			hasGateway = true
+		case unix.RTA_PREFSRC:
+			hasPrefSrc = true
+		}
+	}
+
+	dstLen := msg.Data[1]
+	switch {
+	case hasGateway:
+		return 2
+	case hasPrefSrc && dstLen != 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// nlmsgAlign aligns the length to the netlink message alignment.
+func nlmsgAlign(length int) int {
+	return (length + unix.NLMSG_ALIGNTO - 1) & ^(unix.NLMSG_ALIGNTO - 1)
+}
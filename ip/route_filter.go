@@ -0,0 +1,377 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// RoutePrefixMode is how a prefix selects the routes.
+type RoutePrefixMode int
+
+const (
+	// RoutePrefixExact selects the route of exactly the prefix.
+	RoutePrefixExact RoutePrefixMode = iota
+	// RoutePrefixRoot selects the routes inside the prefix, including
+	// the prefix itself.
+	RoutePrefixRoot
+	// RoutePrefixMatch selects the routes covering the prefix, the same
+	// as looking up the routes for the prefix.
+	RoutePrefixMatch
+)
+
+func (m RoutePrefixMode) String() string {
+	switch m {
+	case RoutePrefixExact:
+		return "exact"
+	case RoutePrefixRoot:
+		return "root"
+	case RoutePrefixMatch:
+		return "match"
+	default:
+		return fmt.Sprintf("%d", m)
+	}
+}
+
+// ParseRoutePrefixMode parses a prefix mode from its name, like `root`.
+func ParseRoutePrefixMode(s string) (RoutePrefixMode, error) {
+	for m := RoutePrefixExact; m <= RoutePrefixMatch; m++ {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid prefix mode \"%s\"", s)
+}
+
+// RouteFilter selects the routes like the selectors of iproute2.
+// The zero value selects the routes of all families in the main table,
+// and a zero field selects any value, except Scope, which is nil for
+// any scope. Cloned selects the cloned routes instead, which are the
+// exceptions of the routes, like the PMTU and redirect ones.
+type RouteFilter struct {
+	Family     int
+	Table      RouteTable
+	AllTables  bool
+	Protocol   RouteProtocol
+	Type       RouteType
+	Scope      *RouteScope
+	OutIfindex int
+	InIfindex  int
+	Gateway    net.IP
+	PrefSrc    net.IP
+	Dst        net.IP
+	DstLen     int
+	DstMode    RoutePrefixMode
+	Cloned     bool
+}
+
+// match reports whether the route is selected by the filter.
+func (f *RouteFilter) match(e *RouteEntry) bool {
+	if f.Family != syscall.AF_UNSPEC && e.Family != f.Family {
+		return false
+	}
+	if f.Cloned != (int(e.Flags)&unix.RTM_F_CLONED != 0) {
+		return false
+	}
+	if !f.AllTables {
+		table := e.Table
+		if table == -1 {
+			table = e.TableID
+		}
+		if table != f.table() {
+			return false
+		}
+	}
+	if f.Protocol != 0 && e.Protocol != f.Protocol {
+		return false
+	}
+	if f.Type != unix.RTN_UNSPEC && e.Type != f.Type {
+		return false
+	}
+	if f.Scope != nil && e.Scope != *f.Scope {
+		return false
+	}
+	if f.OutIfindex != 0 && e.OutIfindex != f.OutIfindex {
+		return false
+	}
+	if f.InIfindex != 0 && e.InIfindex != f.InIfindex {
+		return false
+	}
+	if f.Gateway != nil {
+		gw := e.Gateway
+		if e.Via != nil {
+			gw = e.Via.Addr
+		}
+		if !f.Gateway.Equal(gw) {
+			return false
+		}
+	}
+	if f.PrefSrc != nil && !f.PrefSrc.Equal(e.PrefSrc) {
+		return false
+	}
+	if f.Dst != nil && !f.matchDst(e) {
+		return false
+	}
+	return true
+}
+
+// matchDst reports whether the destination of the route is selected by
+// the prefix of the filter.
+func (f *RouteFilter) matchDst(e *RouteEntry) bool {
+	dst := f.Dst
+	if ip := dst.To4(); ip != nil {
+		dst = ip
+	}
+	if e.Family != syscall.AF_INET && e.Family != syscall.AF_INET6 ||
+		len(dst) != routeAddrLen(e.Family) {
+		return false
+	}
+
+	daddr := e.Daddr
+	if daddr == nil {
+		daddr = make(net.IP, len(dst))
+	}
+	prefix := &net.IPNet{IP: dst, Mask: net.CIDRMask(f.DstLen, len(dst)*8)}
+	route := &net.IPNet{IP: daddr, Mask: net.CIDRMask(e.DstLen, len(daddr)*8)}
+	switch f.DstMode {
+	case RoutePrefixRoot:
+		return e.DstLen >= f.DstLen && prefix.Contains(daddr)
+	case RoutePrefixMatch:
+		return e.DstLen <= f.DstLen && route.Contains(dst)
+	default:
+		return e.DstLen == f.DstLen && prefix.Contains(daddr)
+	}
+}
+
+func routeAddrLen(family int) int {
+	if family == syscall.AF_INET {
+		return net.IPv4len
+	}
+	return net.IPv6len
+}
+
+// table returns the table selected by the filter, which is
+// RT_TABLE_UNSPEC for all tables.
+func (f *RouteFilter) table() RouteTable {
+	switch {
+	case f.AllTables:
+		return unix.RT_TABLE_UNSPEC
+	case f.Table == unix.RT_TABLE_UNSPEC:
+		return unix.RT_TABLE_MAIN
+	default:
+		return f.Table
+	}
+}
+
+// marshalDumpRequest marshals the selectors the kernel filters with when
+// NETLINK_GET_STRICT_CHK is enabled, which are the table, protocol, type
+// and output device. MPLS only supports the output device, and so do the
+// dumps of all families, as MPLS rejects the others if it's loaded.
+// The cloned routes are only dumped with the strict checking.
+func (f *RouteFilter) marshalDumpRequest() ([]byte, error) {
+	var rtmsg iproute2.RtMsg
+	rtmsg.Family = uint8(f.Family)
+	if f.Cloned {
+		rtmsg.Flags = unix.RTM_F_CLONED
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	if f.Family != unix.AF_MPLS && f.Family != syscall.AF_UNSPEC {
+		rtmsg.Protocol = uint8(f.Protocol)
+		rtmsg.Type = uint8(f.Type)
+		if table := f.table(); table < 256 {
+			rtmsg.Table = uint8(table)
+		} else {
+			ae.Uint32(unix.RTA_TABLE, uint32(table))
+		}
+	}
+	if f.OutIfindex != 0 {
+		ae.Uint32(unix.RTA_OIF, uint32(f.OutIfindex))
+	}
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	data, _ := rtmsg.MarshalBinary()
+	return append(data, attrs...), nil
+}
+
+// ListRoutesWithFilter gets the routes selected by the filter, which may
+// be nil to get the routes of all families in the main table.
+//
+// The kernel filters the routes by the table, protocol, type and output
+// device if it supports NETLINK_GET_STRICT_CHK, and the others are
+// filtered here, as are all of them for the dumps of all families.
+func (c *Client) ListRoutesWithFilter(filter *RouteFilter) ([]*RouteEntry, error) {
+	_, entries, err := c.dumpRoutes(filter)
+	return entries, err
+}
+
+// dumpRoutes dumps the routes selected by the filter, and returns the
+// messages of the routes too.
+func (c *Client) dumpRoutes(filter *RouteFilter) ([]netlink.Message, []*RouteEntry, error) {
+	if filter == nil {
+		filter = &RouteFilter{}
+	}
+
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETROUTE
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	// the selectors are ignored or misparsed by the kernel without the
+	// strict checking, so only the family is given then.
+	var rtmsg iproute2.RtMsg
+	rtmsg.Family = uint8(filter.Family)
+	msg.Data, _ = rtmsg.MarshalBinary()
+	if restore, err := c.enableStrictCheck(); err == nil {
+		defer restore()
+
+		data, err := filter.marshalDumpRequest()
+		if err != nil {
+			return nil, nil, err
+		}
+		msg.Data = data
+	}
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routeMsgs := make([]netlink.Message, 0, len(msgs))
+	entries := make([]*RouteEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWROUTE {
+			continue
+		}
+
+		e, ok, err := parseRouteMsg(&msg)
+		if err != nil {
+			return routeMsgs, entries, err
+		}
+		if ok && filter.match(e) {
+			routeMsgs = append(routeMsgs, msg)
+			entries = append(entries, e)
+		}
+	}
+	return routeMsgs, entries, nil
+}
+
+const (
+	procSysNetIPv4RouteFlush = "/proc/sys/net/ipv4/route/flush"
+
+	// routeFlushRounds is the same as iproute2, the routes may be
+	// re-added when flushing, like the routes of a routing daemon.
+	routeFlushRounds = 10
+	// routeFlushBatch is the number of the delete requests sent in one
+	// sendmsg.
+	routeFlushBatch = 128
+)
+
+// FlushRoutes deletes the routes selected by the filter, and returns the
+// number of the deleted ones. The filter may be nil to delete all routes
+// in the main table.
+//
+// The kernel has no bulk deletion for routes, so the delete requests are
+// sent in batches, and the routes are dumped again until none of them is
+// selected, at most 10 rounds.
+//
+// The IPv4 cloned routes can't be deleted one by one, so the whole IPv4
+// route cache is flushed by net.ipv4.route.flush if the filter selects
+// them, and they are not counted.
+func (c *Client) FlushRoutes(filter *RouteFilter) (int, error) {
+	if filter == nil {
+		filter = &RouteFilter{}
+	}
+
+	if filter.Cloned && filter.Family != syscall.AF_INET6 {
+		if err := writeSysctl(procSysNetIPv4RouteFlush, "-1"); err != nil {
+			return 0, err
+		}
+		if filter.Family == syscall.AF_INET {
+			return 0, nil
+		}
+
+		// and then the IPv6 cloned routes.
+		f := *filter
+		f.Family = syscall.AF_INET6
+		filter = &f
+	}
+
+	n := 0
+	for round := 0; round < routeFlushRounds; round++ {
+		entries, err := c.ListRoutesWithFilter(filter)
+		if err != nil {
+			return n, err
+		}
+
+		var msgs []netlink.Message
+		for _, e := range entries {
+			data, err := marshalRouteMsg(e, true)
+			if err != nil {
+				return n, err
+			}
+			msgs = append(msgs, netlink.Message{
+				Header: netlink.Header{
+					Type:  unix.RTM_DELROUTE,
+					Flags: netlink.Request | netlink.Acknowledge,
+				},
+				Data: data,
+			})
+		}
+		if len(msgs) == 0 {
+			return n, nil
+		}
+
+		// keep deleting the others if some routes fail to be deleted.
+		var firstErr error
+		for len(msgs) != 0 {
+			batch := msgs
+			if len(batch) > routeFlushBatch {
+				batch = batch[:routeFlushBatch]
+			}
+			msgs = msgs[len(batch):]
+
+			deleted, err := c.sendBatch(batch)
+			n += deleted
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return n, firstErr
+		}
+	}
+	return n, errors.New("route: flush remains incomplete after 10 rounds")
+}
+
+// sendBatch sends the delete requests in one sendmsg, and returns the
+// number of the succeeded ones. The requests of the gone routes are not
+// counted, like the routes deleted with their nexthops.
+func (c *Client) sendBatch(msgs []netlink.Message) (int, error) {
+	if _, err := c.conn.SendMessages(msgs); err != nil {
+		return 0, err
+	}
+
+	// every request is acked by a single message, and the error of a
+	// request is returned by Receive.
+	n := 0
+	var firstErr error
+	for range msgs {
+		_, err := c.conn.Receive()
+		switch {
+		case err == nil:
+			n++
+		case errors.Is(err, unix.ENOENT), errors.Is(err, unix.ESRCH):
+		case firstErr == nil:
+			firstErr = err
+		}
+	}
+	return n, firstErr
+}
@@ -0,0 +1,152 @@
+package ip
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// RouteGetOptions is the optional selectors of a route lookup,
+// which describe the packet to be routed.
+// The zero values mean the selectors are not used.
+type RouteGetOptions struct {
+	From       net.IP
+	InIfindex  int
+	OutIfindex int
+	Tos        int
+	Mark       uint32
+	UID        *uint32
+	IPProto    int
+	Sport      uint16
+	Dport      uint16
+
+	// VRF is the ifindex of the VRF device, and the lookup is done in
+	// the table of the VRF.
+	VRF int
+	// FibMatch returns the matched FIB entry instead of the resolved
+	// route.
+	FibMatch bool
+	// Connected looks up the route again from the preferred source
+	// address of the first lookup, like the packet is sent back to the
+	// source.
+	Connected bool
+}
+
+// GetRoute asks the kernel which route the packet to the destination
+// would take.
+func (c *Client) GetRoute(dst net.IP, opts *RouteGetOptions) (*RouteEntry, error) {
+	if opts == nil {
+		opts = &RouteGetOptions{}
+	}
+
+	e, err := c.getRoute(dst, opts.From, opts)
+	if err != nil || !opts.Connected || opts.From != nil {
+		return e, err
+	}
+
+	src := e.PrefSrc
+	if src == nil {
+		src = e.Saddr
+	}
+	if src == nil {
+		return nil, errors.New("route: failed to connect the route")
+	}
+	return c.getRoute(dst, src, opts)
+}
+
+func (c *Client) getRoute(dst, src net.IP, opts *RouteGetOptions) (*RouteEntry, error) {
+	family := syscall.AF_INET6
+	if dst.To4() != nil {
+		family = syscall.AF_INET
+	}
+
+	var rtmsg iproute2.RtMsg
+	rtmsg.Family = uint8(family)
+	rtmsg.Tos = uint8(opts.Tos)
+	if opts.FibMatch {
+		rtmsg.Flags |= unix.RTM_F_FIB_MATCH
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	addr, err := familyAddr(family, dst)
+	if err != nil {
+		return nil, err
+	}
+	ae.Bytes(unix.RTA_DST, addr)
+	rtmsg.Dst_len = uint8(len(addr) * 8)
+	if src != nil {
+		addr, err := familyAddr(family, src)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.RTA_SRC, addr)
+		rtmsg.Src_len = uint8(len(addr) * 8)
+	}
+	if opts.InIfindex != 0 {
+		ae.Uint32(unix.RTA_IIF, uint32(opts.InIfindex))
+	}
+	oif := opts.OutIfindex
+	if oif == 0 {
+		oif = opts.VRF
+	}
+	if oif != 0 {
+		ae.Uint32(unix.RTA_OIF, uint32(oif))
+	}
+	if opts.Mark != 0 {
+		ae.Uint32(unix.RTA_MARK, opts.Mark)
+	}
+	if opts.UID != nil {
+		ae.Uint32(unix.RTA_UID, *opts.UID)
+	}
+	if opts.IPProto != 0 {
+		ae.Uint8(unix.RTA_IP_PROTO, uint8(opts.IPProto))
+	}
+	if opts.Sport != 0 {
+		ae.Bytes(unix.RTA_SPORT, htons(opts.Sport))
+	}
+	if opts.Dport != 0 {
+		ae.Bytes(unix.RTA_DPORT, htons(opts.Dport))
+	}
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETROUTE
+	msg.Header.Flags = netlink.Request
+	msg.Data, _ = rtmsg.MarshalBinary()
+	msg.Data = append(msg.Data, attrs...)
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWROUTE {
+			continue
+		}
+
+		e, ok, err := parseRouteMsg(&msg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e, nil
+		}
+	}
+	return nil, errors.New("route: no route in the response")
+}
+
+// htons converts the port to network byte order.
+func htons(port uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, port)
+	return b
+}
@@ -0,0 +1,71 @@
+package ip
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+const (
+	procNetRtAcct = "/proc/net/rt_acct"
+
+	// rtAcctRealms is the number of the realms accounted by the kernel.
+	rtAcctRealms = 256
+	// sizeofIPRtAcct is the size of struct ip_rt_acct.
+	sizeofIPRtAcct = 16
+)
+
+// A RealmAcct is the traffic accounting of a realm, which counts the
+// packets routed by the routes with the realm as the source or the
+// destination realm. The counters are 32 bits in the kernel, so they wrap
+// around.
+type RealmAcct struct {
+	Realm       int
+	BytesTo     uint32
+	PacketsTo   uint32
+	BytesFrom   uint32
+	PacketsFrom uint32
+}
+
+// Sub gets the increments of the counters since the old accounting, with
+// the wrapped counters handled.
+func (a *RealmAcct) Sub(old *RealmAcct) *RealmAcct {
+	return &RealmAcct{
+		Realm:       a.Realm,
+		BytesTo:     a.BytesTo - old.BytesTo,
+		PacketsTo:   a.PacketsTo - old.PacketsTo,
+		BytesFrom:   a.BytesFrom - old.BytesFrom,
+		PacketsFrom: a.PacketsFrom - old.PacketsFrom,
+	}
+}
+
+// IsZero reports whether all the counters are zero.
+func (a *RealmAcct) IsZero() bool {
+	return a.BytesTo == 0 && a.PacketsTo == 0 && a.BytesFrom == 0 && a.PacketsFrom == 0
+}
+
+// ListRealmAccts gets the traffic accounting of all the realms from
+// */proc/net/rt_acct*, like rtacct. It requires CONFIG_IP_ROUTE_CLASSID.
+func ListRealmAccts() ([]*RealmAcct, error) {
+	data, err := ioutil.ReadFile(procNetRtAcct)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < rtAcctRealms*sizeofIPRtAcct {
+		return nil, errors.New("rtacct: not enough data of " + procNetRtAcct)
+	}
+
+	accts := make([]*RealmAcct, 0, rtAcctRealms)
+	for realm := 0; realm < rtAcctRealms; realm++ {
+		b := data[realm*sizeofIPRtAcct:]
+		accts = append(accts, &RealmAcct{
+			Realm:       realm,
+			BytesTo:     nlenc.Uint32(b[0:4]),
+			PacketsTo:   nlenc.Uint32(b[4:8]),
+			BytesFrom:   nlenc.Uint32(b[8:12]),
+			PacketsFrom: nlenc.Uint32(b[12:16]),
+		})
+	}
+	return accts, nil
+}
@@ -0,0 +1,545 @@
+package ip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+// the attributes of fib rules.
+const (
+	FRA_UNSPEC             = 0x0
+	FRA_DST                = 0x1
+	FRA_SRC                = 0x2
+	FRA_IIFNAME            = 0x3
+	FRA_GOTO               = 0x4
+	FRA_UNUSED2            = 0x5
+	FRA_PRIORITY           = 0x6
+	FRA_UNUSED3            = 0x7
+	FRA_UNUSED4            = 0x8
+	FRA_UNUSED5            = 0x9
+	FRA_FWMARK             = 0xa
+	FRA_FLOW               = 0xb
+	FRA_TUN_ID             = 0xc
+	FRA_SUPPRESS_IFGROUP   = 0xd
+	FRA_SUPPRESS_PREFIXLEN = 0xe
+	FRA_TABLE              = 0xf
+	FRA_FWMASK             = 0x10
+	FRA_OIFNAME            = 0x11
+	FRA_PAD                = 0x12
+	FRA_L3MDEV             = 0x13
+	FRA_UID_RANGE          = 0x14
+	FRA_PROTOCOL           = 0x15
+	FRA_IP_PROTO           = 0x16
+	FRA_SPORT_RANGE        = 0x17
+	FRA_DPORT_RANGE        = 0x18
+	FRA_IFNAME             = FRA_IIFNAME
+)
+
+// the flags of fib rules.
+const (
+	FIB_RULE_PERMANENT    = 0x1
+	FIB_RULE_INVERT       = 0x2
+	FIB_RULE_UNRESOLVED   = 0x4
+	FIB_RULE_IIF_DETACHED = 0x8
+	FIB_RULE_DEV_DETACHED = FIB_RULE_IIF_DETACHED
+	FIB_RULE_OIF_DETACHED = 0x10
+	FIB_RULE_FIND_SADDR   = 0x10000
+)
+
+// the actions of fib rules.
+const (
+	FR_ACT_UNSPEC      = 0x0
+	FR_ACT_TO_TBL      = 0x1
+	FR_ACT_GOTO        = 0x2
+	FR_ACT_NOP         = 0x3
+	FR_ACT_RES3        = 0x4
+	FR_ACT_RES4        = 0x5
+	FR_ACT_BLACKHOLE   = 0x6
+	FR_ACT_UNREACHABLE = 0x7
+	FR_ACT_PROHIBIT    = 0x8
+)
+
+const (
+	// the obsolete NAT rules of iproute2 use RTN_NAT as the action, and
+	// the gateway attribute as the address to map to.
+	fibRuleActionNat      = unix.RTN_NAT
+	fibRuleNatGatewayAttr = unix.RTA_GATEWAY
+
+	// fibRuleSuppressDisabled is the suppress_prefixlength and
+	// suppress_ifgroup from kernel if they're not set.
+	fibRuleSuppressDisabled = -1
+)
+
+// RuleAction is the action of a fib rule.
+type RuleAction int
+
+func (a RuleAction) String() string {
+	switch a {
+	case FR_ACT_UNSPEC:
+		return "unspec"
+	case FR_ACT_TO_TBL:
+		return "lookup"
+	case FR_ACT_GOTO:
+		return "goto"
+	case FR_ACT_NOP:
+		return "nop"
+	case FR_ACT_BLACKHOLE:
+		return "blackhole"
+	case FR_ACT_UNREACHABLE:
+		return "unreachable"
+	case FR_ACT_PROHIBIT:
+		return "prohibit"
+	case fibRuleActionNat:
+		return "nat"
+	default:
+		return fmt.Sprintf("%d", a)
+	}
+}
+
+// ParseRuleAction parses a rule action from its name, like `blackhole`,
+// and `unicast` is the same as `lookup` like the route types of iproute2.
+func ParseRuleAction(s string) (RuleAction, error) {
+	if s == "unicast" {
+		return FR_ACT_TO_TBL, nil
+	}
+	for a := RuleAction(FR_ACT_UNSPEC); a <= fibRuleActionNat; a++ {
+		if a.String() == s {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid rule action \"%s\"", s)
+}
+
+// RuleFlags is the flags of a fib rule, like FIB_RULE_INVERT.
+type RuleFlags int
+
+// A RulePortRange is the range of the ports a rule selects.
+type RulePortRange struct {
+	Start uint16
+	End   uint16
+}
+
+// A RuleUIDRange is the range of the uids a rule selects.
+type RuleUIDRange struct {
+	Start uint32
+	End   uint32
+}
+
+// A RuleEntry is a policy routing rule.
+// The table is main if the action is lookup and neither Table nor
+// L3mdev is set when adding, and the action is lookup if it's not set
+// for adding. Priority 0 is not sent, so the kernel picks one for adding,
+// and the rule of priority 0 has no priority from kernel.
+// SuppressPrefixLen and SuppressIfGroup are nil if not set.
+type RuleEntry struct {
+	Family            int
+	Priority          int
+	Flags             RuleFlags
+	Action            RuleAction
+	Src               net.IP
+	SrcLen            int
+	Dst               net.IP
+	DstLen            int
+	Tos               int
+	Mark              uint32
+	Mask              uint32
+	IifName           string
+	OifName           string
+	L3mdev            bool
+	UIDRange          *RuleUIDRange
+	IPProto           int
+	SportRange        *RulePortRange
+	DportRange        *RulePortRange
+	TunID             uint64
+	Table             RouteTable
+	SuppressPrefixLen *int
+	SuppressIfGroup   *LinkGroup
+	Goto              int
+	Realms            uint32
+	NAT               net.IP
+	Protocol          RouteProtocol
+}
+
+// ListRules gets the policy routing rules of the family, which may be
+// AF_UNSPEC for both IPv4 and IPv6.
+func (c *Client) ListRules(family int) ([]*RuleEntry, error) {
+	return c.ListRulesWithFilter(family, nil)
+}
+
+// ListRulesWithFilter gets the policy routing rules of the family which
+// match all the given fields of the filter, like the selectors of
+// iproute2. The filter may be nil to get all the rules.
+func (c *Client) ListRulesWithFilter(family int, filter *RuleEntry) ([]*RuleEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETRULE
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var frh iproute2.FibRuleHdr
+	frh.Family = uint8(family)
+	msg.Data, _ = frh.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*RuleEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWRULE {
+			continue
+		}
+
+		e, ok, err := parseRuleMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok && (filter == nil || ruleMatch(filter, e)) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// ruleMatch reports whether the rule matches all the given fields of the
+// filter, and the priority is given if it's positive.
+func ruleMatch(f, e *RuleEntry) bool {
+	prefixMatch := func(faddr net.IP, flen int, addr net.IP, alen int) bool {
+		return faddr == nil || flen == alen && faddr.Equal(addr)
+	}
+	rangeMatch := func(f, r *RulePortRange) bool {
+		return f == nil || r != nil && *f == *r
+	}
+
+	switch {
+	case f.Priority > 0 && e.Priority != f.Priority,
+		f.Flags&FIB_RULE_INVERT != 0 && e.Flags&FIB_RULE_INVERT == 0,
+		f.Action != FR_ACT_UNSPEC && e.Action != f.Action,
+		!prefixMatch(f.Src, f.SrcLen, e.Src, e.SrcLen),
+		!prefixMatch(f.Dst, f.DstLen, e.Dst, e.DstLen),
+		f.Tos != 0 && e.Tos != f.Tos,
+		f.Mark != 0 && e.Mark != f.Mark,
+		f.Mask != 0 && e.Mask != f.Mask,
+		f.IifName != "" && e.IifName != f.IifName,
+		f.OifName != "" && e.OifName != f.OifName,
+		f.L3mdev && !e.L3mdev,
+		f.UIDRange != nil && (e.UIDRange == nil || *e.UIDRange != *f.UIDRange),
+		f.IPProto != 0 && e.IPProto != f.IPProto,
+		!rangeMatch(f.SportRange, e.SportRange),
+		!rangeMatch(f.DportRange, e.DportRange),
+		f.TunID != 0 && e.TunID != f.TunID,
+		f.Table != unix.RT_TABLE_UNSPEC && e.Table != f.Table,
+		f.SuppressPrefixLen != nil && (e.SuppressPrefixLen == nil || *e.SuppressPrefixLen != *f.SuppressPrefixLen),
+		f.SuppressIfGroup != nil && (e.SuppressIfGroup == nil || *e.SuppressIfGroup != *f.SuppressIfGroup),
+		f.Goto != 0 && e.Goto != f.Goto,
+		f.Realms != 0 && e.Realms != f.Realms,
+		f.NAT != nil && !f.NAT.Equal(e.NAT),
+		f.Protocol != 0 && e.Protocol != f.Protocol:
+		return false
+	}
+	return true
+}
+
+// AddRule adds a policy routing rule, and fails if the rule exists.
+func (c *Client) AddRule(e *RuleEntry) error {
+	return c.modifyRule(unix.RTM_NEWRULE, netlink.Create|netlink.Excl, e)
+}
+
+// DelRule deletes the first rule matching all the given fields of the
+// rule. At least one field besides the family must be given, as the kernel
+// deletes the first rule, which is the local rule, if none is given.
+func (c *Client) DelRule(e *RuleEntry) error {
+	return c.modifyRule(unix.RTM_DELRULE, 0, e)
+}
+
+// FlushRules deletes the rules of the family, and returns the number of
+// the deleted ones. Like iproute2, the rules without priority are kept,
+// which is the local rule of priority 0, while the main and default
+// rules are deleted.
+func (c *Client) FlushRules(family int) (int, error) {
+	return c.FlushRulesWithFilter(family, nil)
+}
+
+// FlushRulesWithFilter deletes the rules of the family which match all
+// the given fields of the filter like FlushRules, and returns the number
+// of the deleted ones.
+func (c *Client) FlushRulesWithFilter(family int, filter *RuleEntry) (int, error) {
+	entries, err := c.ListRulesWithFilter(family, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		if e.Priority == 0 {
+			continue
+		}
+		err := c.DelRule(e)
+		if errors.Is(err, unix.ENOENT) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (c *Client) modifyRule(typ netlink.HeaderType, flags netlink.HeaderFlags, e *RuleEntry) error {
+	var msg netlink.Message
+	msg.Header.Type = typ
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge | flags
+
+	data, err := marshalRuleMsg(e, typ == unix.RTM_DELRULE)
+	if err != nil {
+		return err
+	}
+	if typ == unix.RTM_DELRULE && !ruleHasSelector(data) {
+		return errors.New("rule: no selector of the rule to delete")
+	}
+	msg.Data = data
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+// parseRuleMsg parses a policy routing rule from a netlink message.
+func parseRuleMsg(msg *netlink.Message) (*RuleEntry, bool, error) {
+	var frh iproute2.FibRuleHdr
+	if err := frh.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e RuleEntry
+	e.Family = int(frh.Family)
+	e.SrcLen = int(frh.SrcLen)
+	e.DstLen = int(frh.DstLen)
+	e.Tos = int(frh.Tos)
+	e.Table = RouteTable(frh.Table)
+	e.Action = RuleAction(frh.Action)
+	e.Flags = RuleFlags(frh.Flags)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofFibRuleHdr:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case FRA_PRIORITY:
+			e.Priority = int(ad.Uint32())
+		case FRA_SRC:
+			e.Src = net.IP(ad.Bytes())
+		case FRA_DST:
+			e.Dst = net.IP(ad.Bytes())
+		case FRA_FWMARK:
+			e.Mark = ad.Uint32()
+		case FRA_FWMASK:
+			e.Mask = ad.Uint32()
+		case FRA_IIFNAME:
+			e.IifName = ad.String()
+		case FRA_OIFNAME:
+			e.OifName = ad.String()
+		case FRA_L3MDEV:
+			e.L3mdev = ad.Uint8() != 0
+		case FRA_UID_RANGE:
+			if b := ad.Bytes(); len(b) >= 8 {
+				e.UIDRange = &RuleUIDRange{
+					Start: nlenc.Uint32(b[:4]),
+					End:   nlenc.Uint32(b[4:8]),
+				}
+			}
+		case FRA_IP_PROTO:
+			e.IPProto = int(ad.Uint8())
+		case FRA_SPORT_RANGE:
+			e.SportRange = unmarshalRulePortRange(ad.Bytes())
+		case FRA_DPORT_RANGE:
+			e.DportRange = unmarshalRulePortRange(ad.Bytes())
+		case FRA_TUN_ID:
+			if b := ad.Bytes(); len(b) >= 8 {
+				e.TunID = binary.BigEndian.Uint64(b)
+			}
+		case FRA_TABLE:
+			e.Table = RouteTable(ad.Uint32())
+		case FRA_SUPPRESS_PREFIXLEN:
+			if n := int(int32(ad.Uint32())); n != fibRuleSuppressDisabled {
+				e.SuppressPrefixLen = &n
+			}
+		case FRA_SUPPRESS_IFGROUP:
+			if g := LinkGroup(int32(ad.Uint32())); g != fibRuleSuppressDisabled {
+				e.SuppressIfGroup = &g
+			}
+		case FRA_GOTO:
+			e.Goto = int(ad.Uint32())
+		case FRA_FLOW:
+			e.Realms = ad.Uint32()
+		case fibRuleNatGatewayAttr:
+			e.NAT = net.IP(ad.Bytes())
+		case FRA_PROTOCOL:
+			e.Protocol = RouteProtocol(ad.Uint8())
+		}
+	}
+	err = ad.Err()
+	return &e, err == nil, err
+}
+
+func unmarshalRulePortRange(b []byte) *RulePortRange {
+	if len(b) < 4 {
+		return nil
+	}
+	return &RulePortRange{Start: nlenc.Uint16(b[:2]), End: nlenc.Uint16(b[2:4])}
+}
+
+func marshalRulePortRange(r *RulePortRange) []byte {
+	b := make([]byte, 4)
+	nlenc.PutUint16(b[:2], r.Start)
+	nlenc.PutUint16(b[2:], r.End)
+	return b
+}
+
+// marshalRuleMsg marshals a policy routing rule to the data of a netlink
+// message.
+func marshalRuleMsg(e *RuleEntry, del bool) ([]byte, error) {
+	family := e.Family
+	if family == syscall.AF_UNSPEC {
+		family = ruleFamily(e)
+	}
+
+	var frh iproute2.FibRuleHdr
+	frh.Family = uint8(family)
+	frh.SrcLen = uint8(e.SrcLen)
+	frh.DstLen = uint8(e.DstLen)
+	frh.Tos = uint8(e.Tos)
+	frh.Action = uint8(e.Action)
+	frh.Flags = uint32(e.Flags)
+	if !del && e.Action == FR_ACT_UNSPEC {
+		frh.Action = FR_ACT_TO_TBL
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	table := e.Table
+	if !del && table == unix.RT_TABLE_UNSPEC && !e.L3mdev && frh.Action == FR_ACT_TO_TBL {
+		table = unix.RT_TABLE_MAIN
+	}
+	if table < 256 {
+		frh.Table = uint8(table)
+	} else {
+		frh.Table = unix.RT_TABLE_UNSPEC
+		ae.Uint32(FRA_TABLE, uint32(table))
+	}
+
+	if e.Priority > 0 {
+		ae.Uint32(FRA_PRIORITY, uint32(e.Priority))
+	}
+	addrs := []struct {
+		typ  uint16
+		addr net.IP
+	}{
+		{FRA_SRC, e.Src},
+		{FRA_DST, e.Dst},
+		{fibRuleNatGatewayAttr, e.NAT},
+	}
+	for _, a := range addrs {
+		if a.addr == nil {
+			continue
+		}
+		addr, err := familyAddr(family, a.addr)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(a.typ, addr)
+	}
+	if e.Mark != 0 || e.Mask != 0 {
+		ae.Uint32(FRA_FWMARK, e.Mark)
+	}
+	if e.Mask != 0 {
+		ae.Uint32(FRA_FWMASK, e.Mask)
+	}
+	if e.IifName != "" {
+		ae.String(FRA_IIFNAME, e.IifName)
+	}
+	if e.OifName != "" {
+		ae.String(FRA_OIFNAME, e.OifName)
+	}
+	if e.L3mdev {
+		ae.Uint8(FRA_L3MDEV, 1)
+	}
+	if e.UIDRange != nil {
+		b := make([]byte, 8)
+		nlenc.PutUint32(b[:4], e.UIDRange.Start)
+		nlenc.PutUint32(b[4:], e.UIDRange.End)
+		ae.Bytes(FRA_UID_RANGE, b)
+	}
+	if e.IPProto != 0 {
+		ae.Uint8(FRA_IP_PROTO, uint8(e.IPProto))
+	}
+	if e.SportRange != nil {
+		ae.Bytes(FRA_SPORT_RANGE, marshalRulePortRange(e.SportRange))
+	}
+	if e.DportRange != nil {
+		ae.Bytes(FRA_DPORT_RANGE, marshalRulePortRange(e.DportRange))
+	}
+	if e.TunID != 0 {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, e.TunID)
+		ae.Bytes(FRA_TUN_ID, b)
+	}
+	if e.SuppressPrefixLen != nil {
+		ae.Uint32(FRA_SUPPRESS_PREFIXLEN, uint32(*e.SuppressPrefixLen))
+	}
+	if e.SuppressIfGroup != nil {
+		ae.Uint32(FRA_SUPPRESS_IFGROUP, uint32(*e.SuppressIfGroup))
+	}
+	if e.Goto != 0 {
+		ae.Uint32(FRA_GOTO, uint32(e.Goto))
+	}
+	if e.Realms != 0 {
+		ae.Uint32(FRA_FLOW, e.Realms)
+	}
+	if e.Protocol != 0 {
+		ae.Uint8(FRA_PROTOCOL, uint8(e.Protocol))
+	}
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	data, _ := frh.MarshalBinary()
+	return append(data, attrs...), nil
+}
+
+// ruleHasSelector reports whether the marshaled rule has any field
+// besides the family.
+func ruleHasSelector(data []byte) bool {
+	if len(data) > iproute2.SizeofFibRuleHdr {
+		return true
+	}
+	var frh iproute2.FibRuleHdr
+	if err := frh.UnmarshalBinary(data); err != nil {
+		return false
+	}
+	frh.Family = 0
+	return frh != iproute2.FibRuleHdr{}
+}
+
+// ruleFamily guesses the family of the rule by its addresses.
+func ruleFamily(e *RuleEntry) int {
+	for _, addr := range []net.IP{e.Src, e.Dst} {
+		if addr == nil {
+			continue
+		}
+		if addr.To4() != nil {
+			return syscall.AF_INET
+		}
+		return syscall.AF_INET6
+	}
+	return syscall.AF_INET
+}
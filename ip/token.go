@@ -0,0 +1,187 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/Asphaltt/go-iproute2"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// AddrGenMode is the mode how the kernel generates IPv6 link-local and
+// SLAAC addresses of a link.
+type AddrGenMode uint8
+
+// modes for IFLA_INET6_ADDR_GEN_MODE
+const (
+	AddrGenModeEUI64 AddrGenMode = iota
+	AddrGenModeNone
+	AddrGenModeStablePrivacy
+	AddrGenModeRandom
+)
+
+// String returns the string description of the AddrGenMode.
+func (m AddrGenMode) String() string {
+	modes := []string{"eui64", "none", "stable_secret", "random"}
+	if int(m) >= len(modes) {
+		return fmt.Sprintf("%#x", uint8(m))
+	}
+	return modes[m]
+}
+
+// ParseAddrGenMode gets the AddrGenMode from its string description.
+func ParseAddrGenMode(s string) (AddrGenMode, error) {
+	for m := AddrGenModeEUI64; m <= AddrGenModeRandom; m++ {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid addrgenmode \"%s\"", s)
+}
+
+// A TokenEntry is the IPv6 interface identifier of a link,
+// that's used to form SLAAC addresses instead of the EUI-64 one.
+type TokenEntry struct {
+	Ifindex     int
+	Token       net.IP
+	AddrGenMode AddrGenMode
+}
+
+// ListTokens gets the IPv6 interface identifiers of all links.
+// The identifier is `::` when no token is configured for the link.
+func (c *Client) ListTokens() ([]*TokenEntry, error) {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_GETLINK
+	msg.Header.Flags = netlink.Dump | netlink.Request
+
+	var ifimsg iproute2.IfInfoMsg
+	ifimsg.Family = syscall.AF_INET6
+	msg.Data, _ = ifimsg.MarshalBinary()
+
+	msgs, err := c.conn.Execute(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*TokenEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.RTM_NEWLINK {
+			continue
+		}
+
+		e, ok, err := parseTokenMsg(&msg)
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// GetToken gets the IPv6 interface identifier of the link.
+func (c *Client) GetToken(ifindex int) (*TokenEntry, error) {
+	entries, err := c.ListTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.Ifindex == ifindex {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("token: no IPv6 information of link %d", ifindex)
+}
+
+// SetToken sets the IPv6 interface identifier of the link.
+// Only the lower 64 bits of the token are used by kernel, and
+// `::` removes the token.
+func (c *Client) SetToken(ifindex int, token net.IP) error {
+	if len(token) != net.IPv6len || token.To4() != nil {
+		return errors.New("token: token must be an IPv6 address")
+	}
+
+	return c.setLinkInet6(ifindex, func(ae *netlink.AttributeEncoder) {
+		ae.Bytes(unix.IFLA_INET6_TOKEN, token)
+	})
+}
+
+// SetAddrGenMode sets how the kernel generates IPv6 addresses of the link.
+func (c *Client) SetAddrGenMode(ifindex int, mode AddrGenMode) error {
+	return c.setLinkInet6(ifindex, func(ae *netlink.AttributeEncoder) {
+		ae.Uint8(unix.IFLA_INET6_ADDR_GEN_MODE, uint8(mode))
+	})
+}
+
+// setLinkInet6 sets the IPv6 attributes of the link through
+// IFLA_AF_SPEC.
+func (c *Client) setLinkInet6(ifindex int, fn func(ae *netlink.AttributeEncoder)) error {
+	var msg netlink.Message
+	msg.Header.Type = unix.RTM_SETLINK
+	msg.Header.Flags = netlink.Request | netlink.Acknowledge
+
+	var ifimsg iproute2.IfInfoMsg
+	ifimsg.Index = int32(ifindex)
+	msg.Data, _ = ifimsg.MarshalBinary()
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Nested(unix.IFLA_AF_SPEC, func(nae *netlink.AttributeEncoder) error {
+		nae.Nested(syscall.AF_INET6, func(nae *netlink.AttributeEncoder) error {
+			fn(nae)
+			return nil
+		})
+		return nil
+	})
+	data, err := ae.Encode()
+	if err != nil {
+		return err
+	}
+	msg.Data = append(msg.Data, data...)
+
+	_, err = c.conn.Execute(msg)
+	return err
+}
+
+// parseTokenMsg parses the IPv6 interface identifier from a netlink
+// message of an AF_INET6 link dump.
+func parseTokenMsg(msg *netlink.Message) (*TokenEntry, bool, error) {
+	var ifimsg iproute2.IfInfoMsg
+	if err := ifimsg.UnmarshalBinary(msg.Data); err != nil {
+		return nil, false, err
+	}
+
+	var e TokenEntry
+	e.Ifindex = int(ifimsg.Index)
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[iproute2.SizeofIfInfoMsg:])
+	if err != nil {
+		return &e, false, err
+	}
+
+	found := false
+	for ad.Next() {
+		if ad.Type() != unix.IFLA_PROTINFO {
+			continue
+		}
+
+		ad.Nested(func(nad *netlink.AttributeDecoder) error {
+			for nad.Next() {
+				switch nad.Type() {
+				case unix.IFLA_INET6_TOKEN:
+					e.Token = net.IP(nad.Bytes())
+					found = true
+				case unix.IFLA_INET6_ADDR_GEN_MODE:
+					e.AddrGenMode = AddrGenMode(nad.Uint8())
+				}
+			}
+			return nil
+		})
+	}
+	err = ad.Err()
+	return &e, err == nil && found, err
+}
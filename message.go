@@ -18,6 +18,19 @@ const (
 	SizeofIfaCacheinfo = unix.SizeofIfaCacheinfo
 	SizeofNdMsg        = unix.SizeofNdMsg
 	SizeofRtMsg        = unix.SizeofRtMsg
+	SizeofIfAddrLblMsg = int(unsafe.Sizeof(IfAddrLblMsg{}))
+	SizeofRtNexthop    = unix.SizeofRtNexthop
+	SizeofNhMsg        = int(unsafe.Sizeof(NhMsg{}))
+	SizeofNexthopGrp   = int(unsafe.Sizeof(NexthopGrp{}))
+	SizeofNetconfMsg   = int(unsafe.Sizeof(NetconfMsg{}))
+	SizeofRtaCacheinfo = int(unsafe.Sizeof(RtaCacheinfo{}))
+	SizeofFibRuleHdr   = int(unsafe.Sizeof(FibRuleHdr{}))
+	SizeofRtaMfcStats  = int(unsafe.Sizeof(RtaMfcStats{}))
+	SizeofNdtMsg       = int(unsafe.Sizeof(NdtMsg{}))
+	SizeofNdtConfig    = int(unsafe.Sizeof(NdtConfig{}))
+	SizeofNdtStats     = int(unsafe.Sizeof(NdtStats{}))
+	SizeofPrefixMsg    = int(unsafe.Sizeof(PrefixMsg{}))
+	SizeofIfStatsMsg   = int(unsafe.Sizeof(IfStatsMsg{}))
 )
 
 // An InetDiagReq is a request message for sock diag netlink.
@@ -92,6 +105,17 @@ func (m *NdMsg) MarshalBinary() ([]byte, error) {
 	return struct2bytes(unsafe.Pointer(m), SizeofNdMsg), nil
 }
 
+// UnmarshalBinary unmarshals a neighbour message from byte slice.
+func (m *NdMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNdMsg {
+		return errors.New("NdMsg: not enough data to unmarshal")
+	}
+
+	newNdMsg := (*NdMsg)(unsafe.Pointer(&data[0]))
+	*m = *newNdMsg
+	return nil
+}
+
 // A NdAttrCacheInfo is the cache info in the neighbour/fdb message.
 type NdAttrCacheInfo struct {
 	Confirmed uint32
@@ -199,6 +223,336 @@ func (m *RtMsg) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// RtNexthop is a nexthop of a multipath route, that's an alias of
+// golang.org/x/sys/unix.RtNexthop
+type RtNexthop unix.RtNexthop
+
+// MarshalBinary marshals a route nexthop to byte slice.
+func (m *RtNexthop) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofRtNexthop), nil
+}
+
+// UnmarshalBinary unmarshals a route nexthop from byte slice.
+func (m *RtNexthop) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofRtNexthop {
+		return errors.New("RtNexthop: not enough data to unmarshal")
+	}
+
+	newRtnh := (*RtNexthop)(unsafe.Pointer(&data[0]))
+	*m = *newRtnh
+	return nil
+}
+
+// NhMsg is a nexthop object message, that's an alias of
+// golang.org/x/sys/unix.Nhmsg
+type NhMsg unix.Nhmsg
+
+// MarshalBinary marshals a nexthop message to byte slice.
+func (m *NhMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNhMsg), nil
+}
+
+// UnmarshalBinary unmarshals a nexthop message from byte slice.
+func (m *NhMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNhMsg {
+		return errors.New("NhMsg: not enough data to unmarshal")
+	}
+
+	newNhMsg := (*NhMsg)(unsafe.Pointer(&data[0]))
+	*m = *newNhMsg
+	return nil
+}
+
+// NexthopGrp is a member of a nexthop group, that's an alias of
+// golang.org/x/sys/unix.NexthopGrp
+type NexthopGrp unix.NexthopGrp
+
+// MarshalBinary marshals a nexthop group member to byte slice.
+func (m *NexthopGrp) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNexthopGrp), nil
+}
+
+// UnmarshalBinary unmarshals a nexthop group member from byte slice.
+func (m *NexthopGrp) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNexthopGrp {
+		return errors.New("NexthopGrp: not enough data to unmarshal")
+	}
+
+	newGrp := (*NexthopGrp)(unsafe.Pointer(&data[0]))
+	*m = *newGrp
+	return nil
+}
+
+// A NetconfMsg is a network configuration message, whose struct
+// netconfmsg is padded to the netlink alignment.
+type NetconfMsg struct {
+	Family uint8
+	_      [3]uint8
+}
+
+// MarshalBinary marshals a netconf message to byte slice.
+func (m *NetconfMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNetconfMsg), nil
+}
+
+// UnmarshalBinary unmarshals a netconf message from byte slice.
+func (m *NetconfMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNetconfMsg {
+		return errors.New("NetconfMsg: not enough data to unmarshal")
+	}
+
+	newMsg := (*NetconfMsg)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// A FibRuleHdr is the header of a fib rule message.
+type FibRuleHdr struct {
+	Family uint8
+	DstLen uint8
+	SrcLen uint8
+	Tos    uint8
+	Table  uint8
+	_      [2]uint8
+	Action uint8
+	Flags  uint32
+}
+
+// MarshalBinary marshals a fib rule header to byte slice.
+func (m *FibRuleHdr) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofFibRuleHdr), nil
+}
+
+// UnmarshalBinary unmarshals a fib rule header from byte slice.
+func (m *FibRuleHdr) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofFibRuleHdr {
+		return errors.New("FibRuleHdr: not enough data to unmarshal")
+	}
+
+	newMsg := (*FibRuleHdr)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An RtaCacheinfo is the cache information of a route, the times are in
+// clock ticks.
+type RtaCacheinfo struct {
+	Clntref uint32
+	Lastuse uint32
+	Expires int32
+	Error   uint32
+	Used    uint32
+	Id      uint32
+	Ts      uint32
+	Tsage   uint32
+}
+
+// MarshalBinary marshals a route cache information to byte slice.
+func (m *RtaCacheinfo) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofRtaCacheinfo), nil
+}
+
+// UnmarshalBinary unmarshals a route cache information from byte slice.
+func (m *RtaCacheinfo) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofRtaCacheinfo {
+		return errors.New("RtaCacheinfo: not enough data to unmarshal")
+	}
+
+	newMsg := (*RtaCacheinfo)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An RtaMfcStats is the statistics of a multicast forwarding cache entry.
+type RtaMfcStats struct {
+	Packets uint64
+	Bytes   uint64
+	WrongIf uint64
+}
+
+// MarshalBinary marshals a multicast forwarding cache statistics to byte
+// slice.
+func (m *RtaMfcStats) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofRtaMfcStats), nil
+}
+
+// UnmarshalBinary unmarshals a multicast forwarding cache statistics from
+// byte slice.
+func (m *RtaMfcStats) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofRtaMfcStats {
+		return errors.New("RtaMfcStats: not enough data to unmarshal")
+	}
+
+	newMsg := (*RtaMfcStats)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An NdtMsg is a neighbour table message.
+type NdtMsg struct {
+	Family uint8
+	Pad1   uint8
+	Pad2   uint16
+}
+
+// MarshalBinary marshals a neighbour table message to byte slice.
+func (m *NdtMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNdtMsg), nil
+}
+
+// UnmarshalBinary unmarshals a neighbour table message from byte slice.
+func (m *NdtMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNdtMsg {
+		return errors.New("NdtMsg: not enough data to unmarshal")
+	}
+
+	newMsg := (*NdtMsg)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An NdtConfig is the configuration of a neighbour table, the last flush
+// and the last rand are the milliseconds since then.
+type NdtConfig struct {
+	KeyLen      uint16
+	EntrySize   uint16
+	Entries     uint32
+	LastFlush   uint32
+	LastRand    uint32
+	HashRnd     uint32
+	HashMask    uint32
+	HashChainGC uint32
+	ProxyQlen   uint32
+}
+
+// MarshalBinary marshals a neighbour table configuration to byte slice.
+func (m *NdtConfig) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNdtConfig), nil
+}
+
+// UnmarshalBinary unmarshals a neighbour table configuration from byte
+// slice.
+func (m *NdtConfig) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNdtConfig {
+		return errors.New("NdtConfig: not enough data to unmarshal")
+	}
+
+	newMsg := (*NdtConfig)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An NdtStats is the statistics of a neighbour table.
+type NdtStats struct {
+	Allocs         uint64
+	Destroys       uint64
+	HashGrows      uint64
+	ResFailed      uint64
+	Lookups        uint64
+	Hits           uint64
+	RcvProbesMcast uint64
+	RcvProbesUcast uint64
+	PeriodicGCRuns uint64
+	ForcedGCRuns   uint64
+	TableFulls     uint64
+}
+
+// MarshalBinary marshals a neighbour table statistics to byte slice.
+func (m *NdtStats) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofNdtStats), nil
+}
+
+// UnmarshalBinary unmarshals a neighbour table statistics from byte slice.
+func (m *NdtStats) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofNdtStats {
+		return errors.New("NdtStats: not enough data to unmarshal")
+	}
+
+	newMsg := (*NdtStats)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// A PrefixMsg is an IPv6 prefix message, which is notified when a router
+// advertisement with the prefix information is received.
+type PrefixMsg struct {
+	Family  uint8
+	Pad1    uint8
+	Pad2    uint16
+	Ifindex int32
+	Type    uint8
+	Len     uint8
+	Flags   uint8
+	Pad3    uint8
+}
+
+// MarshalBinary marshals a prefix message to byte slice.
+func (m *PrefixMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofPrefixMsg), nil
+}
+
+// UnmarshalBinary unmarshals a prefix message from byte slice.
+func (m *PrefixMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofPrefixMsg {
+		return errors.New("PrefixMsg: not enough data to unmarshal")
+	}
+
+	newMsg := (*PrefixMsg)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An IfStatsMsg is a link statistics message.
+type IfStatsMsg struct {
+	Family     uint8
+	Pad1       uint8
+	Pad2       uint16
+	Ifindex    uint32
+	FilterMask uint32
+}
+
+// MarshalBinary marshals a link statistics message to byte slice.
+func (m *IfStatsMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofIfStatsMsg), nil
+}
+
+// UnmarshalBinary unmarshals a link statistics message from byte slice.
+func (m *IfStatsMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofIfStatsMsg {
+		return errors.New("IfStatsMsg: not enough data to unmarshal")
+	}
+
+	newMsg := (*IfStatsMsg)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
+// An IfAddrLblMsg is an IPv6 address label message.
+type IfAddrLblMsg struct {
+	Family    uint8
+	Reserved  uint8
+	Prefixlen uint8
+	Flags     uint8
+	Index     uint32
+	Seq       uint32
+}
+
+// MarshalBinary marshals an address label message to byte slice.
+func (m *IfAddrLblMsg) MarshalBinary() ([]byte, error) {
+	return struct2bytes(unsafe.Pointer(m), SizeofIfAddrLblMsg), nil
+}
+
+// UnmarshalBinary unmarshals an address label message from byte slice.
+func (m *IfAddrLblMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < SizeofIfAddrLblMsg {
+		return errors.New("IfAddrLblMsg: not enough data to unmarshal")
+	}
+
+	newMsg := (*IfAddrLblMsg)(unsafe.Pointer(&data[0]))
+	*m = *newMsg
+	return nil
+}
+
 // struct2bytes converts forcely a struct to byte slice in place.
 func struct2bytes(p unsafe.Pointer, length int) []byte {
 	var dataSlice reflect.SliceHeader
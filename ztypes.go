@@ -1,6 +1,10 @@
 package iproute2
 
-import "math/bits"
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+)
 
 // Copying code is to avoid code for different OS platforms.
 
@@ -134,6 +138,9 @@ const (
 	NdaProtocol
 	NdaNhID
 	NdaFdbExtAttrs
+	NdaFlagsExt
+	NdaNdmStateMask
+	NdaNdmFlagsMask
 )
 
 type NtfFlag uint8
@@ -167,6 +174,25 @@ func (f NtfFlag) String() string {
 	return flags[index]
 }
 
+// NtfExtFlag is the extended flags of neighbour in NDA_FLAGS_EXT.
+type NtfExtFlag uint32
+
+const (
+	NtfExtManaged NtfExtFlag = 1 << iota
+	NtfExtLocked
+)
+
+func (f NtfExtFlag) String() string {
+	switch f {
+	case NtfExtManaged:
+		return "managed"
+	case NtfExtLocked:
+		return "locked"
+	default:
+		return ""
+	}
+}
+
 type NudState uint16
 
 const (
@@ -199,6 +225,24 @@ func (s NudState) String() string {
 	return states[index]
 }
 
+// ParseNudState parses a neighbour state from its name or number, like
+// `reachable`.
+func ParseNudState(s string) (NudState, error) {
+	if s == NudNone.String() {
+		return NudNone, nil
+	}
+	for state := NudIncomplete; state <= NudPermanent; state <<= 1 {
+		if state.String() == s {
+			return state, nil
+		}
+	}
+	n, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid nud state \"%s\"", s)
+	}
+	return NudState(n), nil
+}
+
 // copied from src/cmd/vendor/golang.org/x/sys/unix/ztypes_linux.go
 // TODO(Asphaltt): use ztypes_linux.go instead
 const (